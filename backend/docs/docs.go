@@ -23,9 +23,1235 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/": {
+        "/admin/reload": {
             "get": {
-                "description": "获取所有待办事项的列表",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回最近一次重新加载配置的触发方式、时间、结果以及需要重启才能生效的配置项",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "获取重新加载状态",
+                "responses": {
+                    "200": {
+                        "description": "最近一次重新加载的状态",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "重新读取配置文件、环境变量与命令行参数，CORS 来源、Webhook Secret、推送目标与日志级别立即生效\n其余配置项的变化在 restartRequired 中列出，需要重启服务才能生效",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "重新加载配置",
+                "responses": {
+                    "200": {
+                        "description": "重新加载成功，返回重新加载状态",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "新配置无效，继续使用原配置",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/callback": {
+            "get": {
+                "description": "校验 state 与 ID Token，签发会话 Cookie 后跳转回前端",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "OIDC 回调",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "授权码",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "登录时生成的 state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "登录状态无效或已过期",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "身份校验失败",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "get": {
+                "description": "生成 state、nonce 与 PKCE verifier 并跳转到身份提供方登录页",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "OIDC 登录",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "登录成功后跳转的站内路径，例如 /tasks",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "502": {
+                        "description": "身份提供方不可用",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "get": {
+                "description": "清除会话 Cookie 并跳转回前端",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "登出",
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    }
+                }
+            }
+        },
+        "/auth/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回当前请求的认证身份",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "获取当前用户",
+                "responses": {
+                    "200": {
+                        "description": "当前身份",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/failed": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回重试次数耗尽、进入死信状态的通知，最近失败的在前面",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "获取发送失败的通知",
+                "responses": {
+                    "200": {
+                        "description": "成功返回通知列表",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/{id}/requeue": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将死信状态的通知重新入队，尝试次数清零并立即发送",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "重新发送失败的通知",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "通知 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回重新入队后的通知",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "通知不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "通知不处于死信状态",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "按条件查询待办事项。不传 limit 时返回全部匹配记录，与旧版行为一致\n时间范围为左闭右开区间，接受 RFC3339 时间或 YYYY-MM-DD 日期（UTC）\n分页时将响应中的 nextCursor 作为下一次请求的 cursor，排序参数需保持不变",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "获取待办事项列表",
+                "parameters": [
+                    {
+                        "enum": [
+                            "all",
+                            "open",
+                            "completed"
+                        ],
+                        "type": "string",
+                        "description": "完成状态",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "密钥路径前缀",
+                        "name": "pathPrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "关键字，匹配路径、密钥名、项目名、环境与提醒备注",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Infisical 项目 ID",
+                        "name": "projectId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Infisical 环境标识",
+                        "name": "environment",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook 来源标识，传空值时只返回没有来源的待办事项",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间起点（含）",
+                        "name": "createdFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间终点（不含）",
+                        "name": "createdTo",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "完成时间起点（含）",
+                        "name": "completedFrom",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "完成时间终点（不含）",
+                        "name": "completedTo",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "createdAt",
+                            "secretPath"
+                        ],
+                        "type": "string",
+                        "description": "排序字段，默认 id",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "排序方向，默认 desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页条数，最大 200",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分页游标",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回待办事项列表、总数 total 与下一页游标 nextCursor",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "查询参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建一个新的待办事项",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "创建待办事项",
+                "parameters": [
+                    {
+                        "description": "待办事项信息",
+                        "name": "todo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.todoInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回创建的待办事项",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "同一项目、环境下的密钥路径已存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "以 Server-Sent Events 推送待办事项变更，事件名为 todo.created、todo.reset、todo.completed、todo.reopened、todo.deleted\n断线重连时浏览器会自动携带 Last-Event-ID 头，服务端补发错过的事件；\n无法补发时（断线过久或服务重启）发送 resync 事件，客户端应重新拉取列表",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "订阅待办事项变更",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "最后收到的事件 ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "事件流",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/webhook": {
+            "post": {
+                "description": "接收来自 Infisical 的 Webhook 通知并创建或更新待办事项，配置了 Apprise 时同时推送提醒\n注意：此接口使用 HMAC-SHA256 签名验证，需要在 X-Infisical-Signature 头中提供正确的签名\n签名格式：t=\u003ctimestamp\u003e;sha256=\u003csignature\u003e，其中 signature = HMAC-SHA256(secret, requestBody)，只覆盖原始请求体，t= 不参与签名\nsecret 通过环境变量 INFISICAL_WEBHOOK_SECRET 或 INFISICAL_WEBHOOK_SECRETS（多个，用于轮换）配置",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "接收 Infisical Webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook 签名（格式：t=timestamp;sha256=signature）",
+                        "name": "X-Infisical-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook 载荷",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功处理 Webhook",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "签名验证失败、载荷时间戳过期或请求被重放",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据 ID 获取单个待办事项的详细信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "获取单个待办事项",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回待办事项",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定 ID 的待办事项",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "删除待办事项",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功删除",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "请求体携带 isCompleted 时将待办事项设置为目标状态（幂等）\n无请求体时切换当前状态（已完成↔未完成），兼容旧客户端\n携带请求体但未指定 isCompleted 时返回 400\n标记为完成时会记录操作者，并可附带完成备注",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "更新待办事项完成状态",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "可选的目标状态与完成备注",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回更新后的待办事项",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将指定 ID 的待办事项标记为已完成，并记录操作者与可选的完成备注\n对已完成的待办事项重复调用不会改变其状态与完成信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "标记待办事项为已完成",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "可选的完成备注",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.completeInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回更新后的待办事项",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回指定待办事项收到的所有 Webhook 投递记录，按接收时间倒序排列",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "获取待办事项事件时间线",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回事件列表",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/todos/{id}/reopen": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将指定 ID 的待办事项重置为未完成，并清空完成信息\n对未完成的待办事项重复调用不会改变其状态",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "todos"
+                ],
+                "summary": "重新打开待办事项",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "待办事项 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回更新后的待办事项",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "待办事项不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回所有 API Token 的元数据，Token 明文只在创建时返回一次",
                 "consumes": [
                     "application/json"
                 ],
@@ -33,15 +1259,192 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "tokens"
+                ],
+                "summary": "获取 API Token 列表",
+                "responses": {
+                    "200": {
+                        "description": "成功返回 Token 列表",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "生成一个新的 API Token，响应中的 token 字段是明文，只返回这一次，请妥善保存\nrole 可选 viewer / operator / admin，默认 viewer；需要 admin 角色",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "创建 API Token",
+                "parameters": [
+                    {
+                        "description": "Token 信息",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.tokenInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "成功返回创建的 Token（含明文）",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "服务器内部错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定 ID 的 API Token，之后使用该 Token 的请求会被拒绝",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "吊销 API Token",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "获取待办事项列表",
                 "responses": {
                     "200": {
-                        "description": "成功返回待办事项列表",
+                        "description": "成功删除",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Token 不存在",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -54,9 +1457,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "创建一个新的待办事项",
+            }
+        },
+        "/webhook-sources": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回所有 Webhook 来源，Secret 只在创建时返回一次",
                 "consumes": [
                     "application/json"
                 ],
@@ -64,30 +1474,19 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
-                ],
-                "summary": "创建待办事项",
-                "parameters": [
-                    {
-                        "description": "待办事项信息",
-                        "name": "todo",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.todoInput"
-                        }
-                    }
+                    "webhook-sources"
                 ],
+                "summary": "获取 Webhook 来源列表",
                 "responses": {
                     "200": {
-                        "description": "成功返回创建的待办事项",
+                        "description": "成功返回来源列表",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误",
+                    "401": {
+                        "description": "未认证",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -95,8 +1494,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "409": {
-                        "description": "密钥路径已存在",
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -114,11 +1513,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/webhook": {
+            },
             "post": {
-                "description": "接收来自 Infisical 的 Webhook 通知并创建或更新待办事项\n注意：此接口使用 HMAC-SHA256 签名验证，需要在 X-Infisical-Signature 头中提供正确的签名\n签名格式：t=\u003ctimestamp\u003e,v1=\u003csignature\u003e，其中 signature = HMAC-SHA256(secret, timestamp + \".\" + requestBody)\nsecret 通过环境变量 INFISICAL_WEBHOOK_SECRET 配置",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建一个新的 Webhook 来源，Infisical 中的 Webhook 地址填写响应中的 url\nsecret 为空时自动生成，响应中的 secret 字段只返回这一次，请填写到 Infisical 中\nevents 为空表示接受所有支持的事件（secrets.modified、test）；需要 admin 角色",
                 "consumes": [
                     "application/json"
                 ],
@@ -126,30 +1528,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "webhook"
+                    "webhook-sources"
                 ],
-                "summary": "接收 Infisical Webhook",
+                "summary": "创建 Webhook 来源",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Webhook 签名（格式：t=timestamp,v1=signature）",
-                        "name": "X-Infisical-Signature",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "Webhook 载荷",
-                        "name": "payload",
+                        "description": "来源信息",
+                        "name": "source",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.webhookPayload"
+                            "$ref": "#/definitions/handlers.webhookSourceInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功处理 Webhook",
+                        "description": "成功返回创建的来源（含 Secret）",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -165,7 +1560,25 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "签名验证失败",
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "来源标识已存在",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -185,9 +1598,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/{id}": {
-            "get": {
-                "description": "根据 ID 获取单个待办事项的详细信息",
+        "/webhook-sources/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定 ID 的 Webhook 来源，之后发往该来源地址的请求会被拒绝；已创建的待办事项保留来源标记",
                 "consumes": [
                     "application/json"
                 ],
@@ -195,13 +1613,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "webhook-sources"
                 ],
-                "summary": "获取单个待办事项",
+                "summary": "删除 Webhook 来源",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "待办事项 ID",
+                        "description": "来源 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -209,10 +1627,12 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功返回待办事项",
+                        "description": "成功删除",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -224,8 +1644,26 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "404": {
-                        "description": "待办事项不存在",
+                        "description": "来源不存在",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -244,8 +1682,13 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "删除指定 ID 的待办事项",
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "修改来源的名称、Secret、默认项目名或事件过滤规则，未提供的字段保持不变\n修改 Secret 后旧 Secret 立即失效，请同时更新 Infisical 中的配置；需要 admin 角色",
                 "consumes": [
                     "application/json"
                 ],
@@ -253,21 +1696,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "webhook-sources"
                 ],
-                "summary": "删除待办事项",
+                "summary": "修改 Webhook 来源",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "待办事项 ID",
+                        "description": "来源 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "要修改的字段",
+                        "name": "source",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookSourceUpdateInput"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功删除",
+                        "description": "成功返回修改后的来源",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "请求参数错误",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -275,8 +1734,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "请求参数错误",
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -285,7 +1753,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "待办事项不存在",
+                        "description": "来源不存在",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -303,9 +1771,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "切换指定 ID 的待办事项的完成状态（已完成↔未完成）",
+            }
+        },
+        "/webhooks/{source}": {
+            "post": {
+                "description": "与 /todos/webhook 相同，但使用该来源自己的 Secret 验证签名，并按来源的事件过滤规则处理\n载荷中没有 projectName 时使用来源的默认项目名，创建的待办事项会标记来源",
                 "consumes": [
                     "application/json"
                 ],
@@ -313,21 +1783,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "todos"
+                    "webhook"
                 ],
-                "summary": "切换待办事项完成状态",
+                "summary": "接收指定来源的 Infisical Webhook",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "待办事项 ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "Webhook 来源标识",
+                        "name": "source",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook 签名（格式：t=timestamp;sha256=signature）",
+                        "name": "X-Infisical-Signature",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook 载荷",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.webhookPayload"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "成功返回切换后的待办事项",
+                        "description": "成功处理 Webhook",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -342,8 +1828,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "待办事项不存在",
+                    "401": {
+                        "description": "来源不存在、签名验证失败、载荷时间戳过期或请求被重放",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -362,18 +1848,108 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/ws": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "升级为 WebSocket 连接。客户端发送 JSON 消息：\n{\"type\":\"subscribe\",\"id\":\"s1\",\"filter\":{\"projectIds\":[],\"environments\":[\"prod\"],\"pathPrefixes\":[\"/svc\"],\"events\":[]}} 订阅变更；\n{\"type\":\"unsubscribe\",\"id\":\"s1\"} 取消订阅；\n{\"type\":\"complete\",\"id\":\"r1\",\"todoId\":1,\"note\":\"...\"} / {\"type\":\"reopen\",\"id\":\"r2\",\"todoId\":1} 修改完成状态（需要 operator 角色）。\n服务端以 subscribed、unsubscribed、result、event、error 类型的消息响应",
+                "tags": [
+                    "todos"
+                ],
+                "summary": "WebSocket 实时通道",
+                "responses": {
+                    "101": {
+                        "description": "切换协议",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "未认证",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "权限不足",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "handlers.completeInput": {
+            "type": "object",
+            "properties": {
+                "note": {
+                    "description": "Note 是完成备注，例如已经同步更新了哪些服务。仅在标记为完成时保存。",
+                    "type": "string"
+                }
+            }
+        },
         "handlers.todoInput": {
             "type": "object",
             "properties": {
+                "environment": {
+                    "type": "string"
+                },
+                "projectId": {
+                    "description": "以下字段可选，用于区分不同 Infisical 项目或环境下的同名路径。",
+                    "type": "string"
+                },
+                "projectName": {
+                    "type": "string"
+                },
+                "reminderNote": {
+                    "type": "string"
+                },
+                "secretName": {
+                    "type": "string"
+                },
                 "secretPath": {
                     "description": "反射标签指定了 JSON 字段名。",
                     "type": "string"
                 }
             }
         },
+        "handlers.tokenInput": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role 可选 viewer / operator / admin，默认 viewer。",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.updateInput": {
+            "type": "object",
+            "properties": {
+                "isCompleted": {
+                    "description": "IsCompleted 是目标状态。携带请求体时必填；只有完全没有请求体时才切换当前状态，兼容旧客户端。",
+                    "type": "boolean"
+                },
+                "note": {
+                    "description": "Note 是完成备注，仅在标记为完成时保存。",
+                    "type": "string"
+                }
+            }
+        },
         "handlers.webhookPayload": {
             "type": "object",
             "properties": {
@@ -387,7 +1963,6 @@ const docTemplate = `{
                             "type": "string"
                         },
                         "projectId": {
-                            "description": "以下字段目前未使用，但保留方便将来扩展",
                             "type": "string"
                         },
                         "projectName": {
@@ -408,6 +1983,59 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "handlers.webhookSourceInput": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projectLabel": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret 为空时自动生成。",
+                    "type": "string"
+                },
+                "slug": {
+                    "description": "Slug 是来源在 URL 中的标识，创建后不能修改。",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.webhookSourceUpdateInput": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "projectLabel": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "API Token，格式：Bearer \u003ctoken\u003e",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -416,7 +2044,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/api/todos",
+	BasePath:         "/api",
 	Schemes:          []string{"http", "https"},
 	Title:            "Infisical Notification API",
 	Description:      "这是一个接收 Infisical Webhook 通知并管理 Todo 任务的后端服务",
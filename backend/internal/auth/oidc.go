@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig 是 OIDC 授权码登录所需的参数。
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// RoleClaim 是 ID Token 中保存用户组的 claim 名称，例如 "groups"。
+	RoleClaim string
+	// AdminGroups 与 OperatorGroups 中的组会分别映射为 admin 与 operator 角色。
+	AdminGroups    []string
+	OperatorGroups []string
+	// DefaultRole 是不属于任何映射组的用户的角色。
+	DefaultRole Role
+}
+
+// Identity 是从 ID Token 中提取的用户身份。
+type Identity struct {
+	Subject string
+	Name    string
+	Role    Role
+}
+
+// OIDCClient 封装了 OIDC Provider 的发现、授权码交换与 ID Token 校验。
+// Provider 的发现文档在第一次使用时才拉取，失败后下次请求会重试，
+// 这样身份提供方暂时不可用时不会阻止后端启动。
+type OIDCClient struct {
+	cfg OIDCConfig
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCClient 创建 OIDCClient 实例。
+func NewOIDCClient(cfg OIDCConfig) *OIDCClient {
+	return &OIDCClient{cfg: cfg}
+}
+
+// AuthCodeURL 返回跳转到身份提供方登录页的地址。
+// 使用 PKCE (S256) 防止授权码被截获后滥用。
+func (c *OIDCClient) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oauthCfg, _, err := c.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthCfg.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 用授权码换取 Token，并校验 ID Token 的签名、受众、过期时间与 nonce。
+func (c *OIDCClient) Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error) {
+	oauthCfg, idVerifier, err := c.load(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := oauthCfg.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := idVerifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, err
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+		Email             string `json:"email"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+
+	// 依次选择最便于阅读的名称
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Email
+	}
+	if name == "" {
+		name = claims.Name
+	}
+	if name == "" {
+		name = idToken.Subject
+	}
+
+	var rawClaims map[string]any
+	if err := idToken.Claims(&rawClaims); err != nil {
+		return Identity{}, err
+	}
+
+	return Identity{Subject: idToken.Subject, Name: name, Role: c.roleFor(groupsFromClaim(rawClaims[c.cfg.RoleClaim]))}, nil
+}
+
+// roleFor 根据用户所属的组计算角色，取最高的匹配结果。
+func (c *OIDCClient) roleFor(groups []string) Role {
+	role := c.cfg.DefaultRole
+	for _, group := range groups {
+		if slices.Contains(c.cfg.AdminGroups, group) {
+			return RoleAdmin
+		}
+		if slices.Contains(c.cfg.OperatorGroups, group) && !role.Allows(RoleOperator) {
+			role = RoleOperator
+		}
+	}
+	return role
+}
+
+// groupsFromClaim 兼容字符串数组与单个字符串两种 claim 格式。
+func groupsFromClaim(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		groups := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	default:
+		return nil
+	}
+}
+
+// load 在首次调用时拉取 Provider 的发现文档，成功后缓存结果。
+func (c *OIDCClient) load(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.oauth != nil {
+		return c.oauth, c.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, c.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scopes := c.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	c.oauth = &oauth2.Config{
+		ClientID:     c.cfg.ClientID,
+		ClientSecret: c.cfg.ClientSecret,
+		RedirectURL:  c.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	c.verifier = provider.Verifier(&oidc.Config{ClientID: c.cfg.ClientID})
+	return c.oauth, c.verifier, nil
+}
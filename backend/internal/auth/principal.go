@@ -0,0 +1,45 @@
+// Package auth 提供身份认证相关的公共能力：
+// 当前请求的身份 (Principal) 以及 API Token 的生成与哈希。
+// 它不依赖 handlers 或 middleware，因此两者都可以引用它而不会产生循环依赖。
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// 认证方式。
+const (
+	// MethodToken 表示通过 Bearer API Token 认证。
+	MethodToken = "token"
+	// MethodSession 表示通过 OIDC 登录后的会话 Cookie 认证。
+	MethodSession = "session"
+)
+
+// principalKey 是 Principal 在 gin.Context 中的存储键。
+const principalKey = "auth.principal"
+
+// Principal 描述已通过认证的调用方。
+type Principal struct {
+	// Subject 是调用方的唯一标识，例如 "token:3" 或 "oidc:<sub>"。
+	Subject string
+	// Name 是便于阅读的名称，例如 Token 的名称或 OIDC 用户名。
+	Name string
+	// Method 是认证方式，取值见 Method* 常量。
+	Method string
+	// Role 是调用方的权限等级。
+	Role Role
+}
+
+// SetPrincipal 将认证结果保存到请求上下文中，供后续 Handler 读取。
+func SetPrincipal(c *gin.Context, p Principal) {
+	c.Set(principalKey, p)
+}
+
+// PrincipalFrom 从请求上下文中读取认证结果。
+// 未经过认证中间件的请求（如 Webhook）返回 false。
+func PrincipalFrom(c *gin.Context) (Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := value.(Principal)
+	return p, ok
+}
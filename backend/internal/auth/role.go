@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Role 表示调用方的权限等级，高等级包含低等级的全部权限。
+type Role string
+
+const (
+	// RoleViewer 只能查看待办事项（List、Get、事件时间线）。
+	RoleViewer Role = "viewer"
+	// RoleOperator 在 viewer 基础上可以切换待办事项的完成状态、处理失败的通知。
+	RoleOperator Role = "operator"
+	// RoleAdmin 拥有全部权限：创建、删除待办事项，管理 API Token 与 Webhook 相关配置。
+	RoleAdmin Role = "admin"
+)
+
+// roleLevels 定义角色的权限高低。
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole 解析角色名称（不区分大小写），未知角色返回错误。
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", value)
+	}
+	return role, nil
+}
+
+// Allows 判断当前角色是否满足 required 要求的最低权限。
+// 未知角色没有任何权限。
+func (r Role) Allows(required Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[required]
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// SessionCookieName 是登录会话 Cookie 的名称。
+const SessionCookieName = "itn_session"
+
+// ErrInvalidSession 表示 Cookie 被篡改、格式错误或已过期。
+var ErrInvalidSession = errors.New("invalid session")
+
+// Session 是保存在签名 Cookie 中的登录会话。
+// 会话本身不落库，服务端只校验签名与过期时间。
+type Session struct {
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role"`
+	ExpiresAt int64  `json:"exp"` // Unix 秒
+}
+
+// Signer 使用 HMAC-SHA256 对任意 JSON 数据签名和验签。
+// Cookie 格式：base64url(json) + "." + base64url(hmac)。
+// 除会话外，OIDC 登录过程中的 state/nonce 也用它保存在短期 Cookie 中。
+type Signer struct {
+	key []byte
+}
+
+// NewSigner 创建 Signer 实例，secret 应当是足够长的随机字符串。
+func NewSigner(secret string) *Signer {
+	return &Signer{key: []byte(secret)}
+}
+
+// Sign 将 value 序列化为 JSON 并附加签名。
+func (s *Signer) Sign(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload)), nil
+}
+
+// Verify 校验签名并将 JSON 反序列化到 out 中。
+func (s *Signer) Verify(signed string, out any) error {
+	payload, sig, found := strings.Cut(signed, ".")
+	if !found {
+		return ErrInvalidSession
+	}
+
+	decodedSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(decodedSig, s.mac(payload)) {
+		return ErrInvalidSession
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidSession
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return ErrInvalidSession
+	}
+	return nil
+}
+
+// EncodeSession 对会话签名，返回 Cookie 值。
+func (s *Signer) EncodeSession(session Session) (string, error) {
+	return s.Sign(session)
+}
+
+// DecodeSession 校验 Cookie 值并返回未过期的会话。
+func (s *Signer) DecodeSession(value string, now time.Time) (Session, error) {
+	var session Session
+	if err := s.Verify(value, &session); err != nil {
+		return Session{}, err
+	}
+	if session.Subject == "" || now.Unix() >= session.ExpiresAt {
+		return Session{}, ErrInvalidSession
+	}
+	if _, err := ParseRole(string(session.Role)); err != nil {
+		return Session{}, ErrInvalidSession
+	}
+	return session, nil
+}
+
+func (s *Signer) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// tokenPrefix 让 Token 一眼可辨认，也便于密钥扫描工具识别泄露。
+const tokenPrefix = "itn_"
+
+// tokenDisplayLength 是保存在数据库中、用于展示的 Token 前缀长度。
+const tokenDisplayLength = 12
+
+// GenerateToken 生成一个新的随机 API Token（明文）。
+// 明文只在创建时返回一次，数据库中只保存它的哈希。
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken 计算 Token 的 SHA-256 十六进制摘要。
+// Token 本身是高熵随机串，不需要加盐或慢哈希。
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix 返回 Token 的前若干位，用于在列表中区分不同 Token。
+func DisplayPrefix(token string) string {
+	if len(token) <= tokenDisplayLength {
+		return token
+	}
+	return token[:tokenDisplayLength]
+}
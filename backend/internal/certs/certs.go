@@ -0,0 +1,259 @@
+// Package certs 负责 HTTPS 所需的证书：从文件加载并在文件变化时热更新、加载客户端 CA，
+// 以及在开发时生成自签名证书。
+// 没有反向代理的部署可以直接由后端提供 HTTPS，公网可访问的 Webhook 端点不应使用明文 HTTP。
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval 是检查证书文件是否变化的默认间隔。
+const DefaultReloadInterval = 30 * time.Second
+
+// selfSignedValidity 是自签名证书的有效期。证书只保存在内存中，每次启动重新生成。
+const selfSignedValidity = 30 * 24 * time.Hour
+
+// Options 描述 HTTPS 的配置，由 config.Config 提供。
+type Options struct {
+	// CertFile 与 KeyFile 是 PEM 格式的证书（可以包含中间证书）与私钥。
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile 是签发客户端证书的 CA（PEM 格式），配置后要求 API 请求出示由它签发的客户端证书。
+	ClientCAFile string
+
+	// SelfSigned 为 true 时在内存中生成自签名证书，仅用于开发。
+	SelfSigned bool
+
+	// ReloadInterval 是检查证书文件是否变化的间隔，0 表示使用 DefaultReloadInterval。
+	ReloadInterval time.Duration
+}
+
+// ServerConfig 根据配置创建 http.Server 使用的 tls.Config。
+// 使用证书文件时同时返回 Reloader，调用方需要运行 Reloader.Run 才能在证书更新后自动生效；
+// 使用自签名证书时 Reloader 为 nil。
+//
+// 配置了 ClientCAFile 时，TLS 握手只校验客户端出示的证书（VerifyClientCertIfGiven），
+// 不强制要求出示：Infisical 发送 Webhook 时不会携带客户端证书。
+// 是否必须出示由路由层的 middleware.RequireClientCert 按路由决定。
+func ServerConfig(opts Options) (*tls.Config, *Reloader, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	var reloader *Reloader
+	if opts.SelfSigned {
+		cert, err := SelfSigned(selfSignedHosts())
+		if err != nil {
+			return nil, nil, err
+		}
+		slog.Warn("使用自签名证书提供 HTTPS，仅适用于开发环境", "sha256", Fingerprint(cert))
+		cfg.Certificates = []tls.Certificate{cert}
+	} else {
+		var err error
+		reloader, err = NewReloader(opts.CertFile, opts.KeyFile, opts.ReloadInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.GetCertificate = reloader.GetCertificate
+	}
+
+	if opts.ClientCAFile != "" {
+		pool, err := loadCertPool(opts.ClientCAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg, reloader, nil
+}
+
+// Reloader 持有从文件加载的证书，并在文件变化时重新加载。
+// 它通过比较文件的修改时间与大小判断变化，不依赖文件系统通知，
+// 因此同样适用于 Kubernetes Secret 这类通过替换符号链接更新的挂载方式。
+type Reloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	version string // 加载当前证书时两个文件的修改时间与大小
+}
+
+// NewReloader 加载证书与私钥。首次加载失败直接返回错误，避免带着无效的证书启动。
+func NewReloader(certFile, keyFile string, interval time.Duration) (*Reloader, error) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	r := &Reloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate，每次握手返回当前的证书。
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Run 定期检查证书文件，发生变化时重新加载，直到 ctx 取消。
+// 重新加载失败（例如证书与私钥只更新了其中一个）时继续使用原来的证书，下次检查时重试。
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reload()
+			if err != nil {
+				slog.Warn("重新加载 TLS 证书失败，继续使用原证书", "cert_file", r.certFile, "error", err)
+			} else if reloaded {
+				slog.Info("TLS 证书已重新加载", "cert_file", r.certFile, "not_after", r.notAfter())
+			}
+		}
+	}
+}
+
+// reload 在文件发生变化时重新加载证书，返回是否加载了新的证书。
+func (r *Reloader) reload() (bool, error) {
+	version, err := fileVersion(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := version == r.version
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("load tls key pair: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.version = version
+	r.mu.Unlock()
+	return true, nil
+}
+
+// notAfter 返回当前证书的过期时间，用于日志。
+func (r *Reloader) notAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil || r.cert.Leaf == nil {
+		return time.Time{}
+	}
+	return r.cert.Leaf.NotAfter
+}
+
+// fileVersion 返回代表若干文件当前内容版本的字符串。
+func fileVersion(paths ...string) (string, error) {
+	var version string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		version += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return version, nil
+}
+
+// loadCertPool 从 PEM 文件加载 CA 证书。
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client ca %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// SelfSigned 生成一个覆盖 hosts（域名或 IP）的自签名证书。
+func SelfSigned(hosts []string) (tls.Certificate, error) {
+	if len(hosts) == 0 {
+		return tls.Certificate{}, errors.New("self-signed certificate needs at least one host")
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hosts[0], Organization: []string{"infisical-notification (self-signed)"}},
+		NotBefore:    now.Add(-time.Hour), // 容忍客户端时钟稍慢
+		NotAfter:     now.Add(selfSignedValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("create certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// selfSignedHosts 返回自签名证书覆盖的地址：本机回环地址与主机名（容器中即容器名称或 ID）。
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		hosts = append(hosts, hostname)
+	}
+	return hosts
+}
+
+// Fingerprint 返回证书的 SHA-256 指纹，用于在客户端核对自签名证书。
+func Fingerprint(cert tls.Certificate) string {
+	if len(cert.Certificate) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	return hex.EncodeToString(sum[:])
+}
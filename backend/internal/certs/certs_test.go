@@ -0,0 +1,121 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair 生成一个自签名证书，以 PEM 格式写入 dir，修改时间设为 modTime。
+func writeKeyPair(t *testing.T, dir string, modTime time.Time) (certFile, keyFile string, cert tls.Certificate) {
+	t.Helper()
+	cert, err := SelfSigned([]string{"localhost"})
+	if err != nil {
+		t.Fatalf("SelfSigned: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writePEM(t, certFile, "CERTIFICATE", cert.Certificate[0], modTime)
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER, modTime)
+	return certFile, keyFile, cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	// 显式设置修改时间，不依赖文件系统的时间精度
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile, first := writeKeyPair(t, dir, start)
+
+	reloader, err := NewReloader(certFile, keyFile, time.Minute)
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	current := func() string {
+		cert, err := reloader.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+		return Fingerprint(*cert)
+	}
+	if current() != Fingerprint(first) {
+		t.Fatal("initial certificate does not match the files")
+	}
+
+	// 文件没有变化时不重新加载
+	if reloaded, err := reloader.reload(); err != nil || reloaded {
+		t.Fatalf("reload unchanged = %v, %v; want false, nil", reloaded, err)
+	}
+
+	// 续期后新的握手使用新证书
+	_, _, second := writeKeyPair(t, dir, start.Add(time.Minute))
+	if reloaded, err := reloader.reload(); err != nil || !reloaded {
+		t.Fatalf("reload renewed = %v, %v; want true, nil", reloaded, err)
+	}
+	if current() != Fingerprint(second) {
+		t.Fatal("certificate not replaced after renewal")
+	}
+
+	// 只更新了证书、私钥还是旧的：加载失败，继续使用原证书
+	third, err := SelfSigned([]string{"localhost"})
+	if err != nil {
+		t.Fatalf("SelfSigned: %v", err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", third.Certificate[0], start.Add(2*time.Minute))
+	if _, err := reloader.reload(); err == nil {
+		t.Fatal("reload with mismatched key succeeded")
+	}
+	if current() != Fingerprint(second) {
+		t.Fatal("certificate changed after a failed reload")
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeKeyPair(t, dir, time.Now())
+
+	cfg, reloader, err := ServerConfig(Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("ServerConfig: %v", err)
+	}
+	if reloader == nil || cfg.GetCertificate == nil || cfg.ClientAuth != tls.NoClientCert {
+		t.Fatalf("ServerConfig = %+v, reloader %v; want GetCertificate without client auth", cfg, reloader)
+	}
+
+	// 配置客户端 CA 时只校验出示的证书，不强制要求出示
+	cfg, _, err = ServerConfig(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile})
+	if err != nil {
+		t.Fatalf("ServerConfig with client CA: %v", err)
+	}
+	if cfg.ClientAuth != tls.VerifyClientCertIfGiven || cfg.ClientCAs == nil {
+		t.Fatalf("ClientAuth = %v, want VerifyClientCertIfGiven with a CA pool", cfg.ClientAuth)
+	}
+	if _, _, err := ServerConfig(Options{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile}); err == nil {
+		t.Fatal("ServerConfig accepted a client CA file without certificates")
+	}
+
+	// 自签名模式不需要证书文件，也没有 Reloader
+	cfg, reloader, err = ServerConfig(Options{SelfSigned: true})
+	if err != nil {
+		t.Fatalf("ServerConfig self-signed: %v", err)
+	}
+	if reloader != nil || len(cfg.Certificates) != 1 || Fingerprint(cfg.Certificates[0]) == Fingerprint(cert) {
+		t.Fatalf("self-signed ServerConfig = %+v, reloader %v; want one fresh in-memory certificate", cfg, reloader)
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redactedValue 是 config print --redacted 中代替敏感信息的占位符。
+const redactedValue = "******"
+
+// RunCommand 实现 config 子命令，args 是 config 之后的参数，结果输出到 out。
+//
+//	config validate              加载并校验配置，配置无效时返回错误
+//	config print [--redacted]    输出生效的配置与每一项的来源，--redacted 隐藏 Secret、Token 等敏感信息
+//
+// 输出使用配置文件的 YAML 格式，可以直接保存为配置文件。
+func RunCommand(src *Source, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("用法: config validate | print [--redacted]")
+	}
+
+	// 子命令之后的参数只接受 --redacted，配置相关的参数需要写在 config 之前
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	redacted := fs.Bool("redacted", false, "隐藏 Secret、Token 等敏感信息（仅 print）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("多余的参数: %q", fs.Args())
+	}
+
+	switch args[0] {
+	case "validate":
+		if _, err := Load(src); err != nil {
+			return err
+		}
+		if src.File != "" {
+			fmt.Fprintf(out, "配置有效（配置文件: %s）\n", src.File)
+		} else {
+			fmt.Fprintln(out, "配置有效（未使用配置文件）")
+		}
+		return nil
+
+	case "print":
+		cfg, err := Load(src)
+		if err != nil {
+			return err
+		}
+		return printConfig(out, src, &cfg, *redacted)
+
+	default:
+		return fmt.Errorf("未知的 config 子命令: %q", args[0])
+	}
+}
+
+// printConfig 按配置文件的层级输出生效的配置，每一项后面注明来源（default、file、env 或 flag）。
+func printConfig(out io.Writer, src *Source, cfg *Config, redacted bool) error {
+	if src.File != "" {
+		fmt.Fprintf(out, "# 配置文件: %s\n", src.File)
+	}
+
+	section := ""
+	for _, s := range settings {
+		value := s.value(cfg)
+		if redacted && s.redact != nil && !isEmpty(value) {
+			value = s.redact(value)
+		}
+
+		name := s.key
+		indent := ""
+		if parent, child, nested := strings.Cut(s.key, "."); nested {
+			if parent != section {
+				fmt.Fprintf(out, "%s:\n", parent)
+				section = parent
+			}
+			name, indent = child, "  "
+		}
+		if _, err := fmt.Fprintf(out, "%s%s: %s  # %s\n", indent, name, formatValue(value), src.origin(s.env)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatValue 把配置值格式化为 YAML 字面量。
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case time.Duration:
+		return strconv.Quote(v.String())
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, strconv.Quote(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, strconv.Quote(key)+": "+strconv.Quote(v[key]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isEmpty 判断配置值是否为空，空值不需要隐藏。
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case map[string]string:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// redactAll 隐藏整个值。
+func redactAll(any) any {
+	return redactedValue
+}
+
+// redactDSN 只隐藏连接串中的密码，保留主机与数据库名，便于排查连接问题。
+func redactDSN(value any) any {
+	dsn, _ := value.(string)
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return redactedValue
+	}
+	// postgres 也支持通过 password 查询参数传入密码
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", "xxxxx")
+		u.RawQuery = query.Encode()
+	}
+	// URL.Redacted 把密码替换为 xxxxx，这里统一换成与其他敏感信息相同的占位符
+	return strings.ReplaceAll(u.Redacted(), "xxxxx", redactedValue)
+}
+
+// redactWebhookSecrets 隐藏每个 Webhook Secret 的内容，保留 ID 与过期时间，便于核对轮换状态。
+func redactWebhookSecrets(value any) any {
+	entries, _ := value.([]string)
+	redacted := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) >= 2 {
+			parts[1] = redactedValue
+		}
+		redacted = append(redacted, strings.Join(parts, ":"))
+	}
+	return redacted
+}
+
+// redactMapValues 隐藏键值对中的值，保留键名。
+func redactMapValues(value any) any {
+	entries, _ := value.(map[string]string)
+	redacted := make(map[string]string, len(entries))
+	for key := range entries {
+		redacted[key] = redactedValue
+	}
+	return redacted
+}
+
+// defaultWebhookSecret 返回 INFISICAL_WEBHOOK_SECRET 对应的 Secret。
+func defaultWebhookSecret(c *Config) any {
+	for _, key := range c.WebhookSecrets {
+		if key.ID == defaultWebhookKeyID {
+			return key.Secret
+		}
+	}
+	return ""
+}
+
+// extraWebhookSecrets 按 INFISICAL_WEBHOOK_SECRETS 的格式返回其余的 Secret。
+func extraWebhookSecrets(c *Config) any {
+	var entries []string
+	for _, key := range c.WebhookSecrets {
+		if key.ID == defaultWebhookKeyID {
+			continue
+		}
+		entry := key.ID + ":" + key.Secret
+		if !key.ExpiresAt.IsZero() {
+			entry += ":" + key.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
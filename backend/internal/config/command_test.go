@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunCommandValidate(t *testing.T) {
+	var out bytes.Buffer
+	if err := RunCommand(newSource(nil), []string{"validate"}, &out); err != nil || !strings.Contains(out.String(), "配置有效") {
+		t.Fatalf("validate = %v, %q", err, out.String())
+	}
+
+	out.Reset()
+	if err := RunCommand(newSource(map[string]string{"APP_ENV": "staging"}), []string{"validate"}, &out); err == nil || !strings.Contains(err.Error(), "APP_ENV") {
+		t.Fatalf("validate with an invalid value = %v, want an error naming APP_ENV", err)
+	}
+
+	for _, args := range [][]string{nil, {"show"}, {"validate", "extra"}, {"print", "--verbose"}} {
+		if err := RunCommand(newSource(nil), args, &out); err == nil {
+			t.Errorf("RunCommand(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestRunCommandPrint(t *testing.T) {
+	src := newSource(map[string]string{
+		"TODO_DB_DSN":                "postgres://todo:dbpass@db:5432/todo?sslmode=disable",
+		"INFISICAL_WEBHOOK_SECRET":   "whsecret0",
+		"INFISICAL_WEBHOOK_SECRETS":  "next:whsecret1:2030-01-01",
+		"OTEL_EXPORTER_OTLP_HEADERS": "x-api-key=otlpkey",
+		"TODO_ADMIN_TOKEN":           "admintoken",
+		"OIDC_ISSUER_URL":            "https://id.example.com",
+		"OIDC_CLIENT_ID":             "todo",
+		"OIDC_CLIENT_SECRET":         "oidcsecret",
+		"OIDC_REDIRECT_URL":          "https://todo.example.com/auth/callback",
+		"SESSION_SECRET":             strings.Repeat("x", minSessionSecretLength),
+	})
+	secrets := []string{"dbpass", "whsecret0", "whsecret1", "otlpkey", "admintoken", "oidcsecret", strings.Repeat("x", minSessionSecretLength)}
+
+	var plain bytes.Buffer
+	if err := RunCommand(src, []string{"print"}, &plain); err != nil {
+		t.Fatalf("print: %v", err)
+	}
+	for _, secret := range secrets {
+		if !strings.Contains(plain.String(), secret) {
+			t.Errorf("print output does not contain %q", secret)
+		}
+	}
+
+	var redacted bytes.Buffer
+	if err := RunCommand(src, []string{"print", "--redacted"}, &redacted); err != nil {
+		t.Fatalf("print --redacted: %v", err)
+	}
+	output := redacted.String()
+	for _, secret := range secrets {
+		if strings.Contains(output, secret) {
+			t.Errorf("redacted output contains %q:\n%s", secret, output)
+		}
+	}
+	// 隐藏敏感信息时保留排查问题需要的部分：连接串的主机、Webhook Secret 的 ID 与过期时间、请求头名称
+	for _, want := range []string{
+		`dsn: "postgres://todo:******@db:5432/todo?sslmode=disable"  # env`,
+		`secrets: ["next:******:2030-01-01T00:00:00Z"]  # env`,
+		`headers: {"x-api-key": "******"}  # env`,
+		`client_secret: "******"  # env`,
+		`bind_addr: ":8080"  # default`,
+		`client_id: "todo"  # env`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("redacted output does not contain %q:\n%s", want, output)
+		}
+	}
+}
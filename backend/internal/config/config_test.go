@@ -0,0 +1,311 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/signature"
+)
+
+// newSource 返回只包含 values（环境变量名 → 值）的配置来源，所有值都视为来自环境变量。
+func newSource(values map[string]string) *Source {
+	src := &Source{values: map[string]string{}, origins: map[string]string{}}
+	for env, value := range values {
+		src.set(env, value, originEnv)
+	}
+	return src
+}
+
+func TestLoadWebhookSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    []signature.Key
+		wantErr string
+	}{
+		{name: "none", values: nil},
+		{
+			name:   "single secret",
+			values: map[string]string{"INFISICAL_WEBHOOK_SECRET": "s0"},
+			want:   []signature.Key{{ID: "default", Secret: "s0"}},
+		},
+		{
+			name: "default and list",
+			values: map[string]string{
+				"INFISICAL_WEBHOOK_SECRET":  "s0",
+				"INFISICAL_WEBHOOK_SECRETS": " new : s1 , old:s2:2025-01-31, rfc:s3:2025-01-31T12:00:00+08:00",
+			},
+			want: []signature.Key{
+				{ID: "default", Secret: "s0"},
+				{ID: "new", Secret: "s1"},
+				{ID: "old", Secret: "s2", ExpiresAt: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)},
+				{ID: "rfc", Secret: "s3", ExpiresAt: time.Date(2025, 1, 31, 4, 0, 0, 0, time.UTC)},
+			},
+		},
+		{name: "missing secret", values: map[string]string{"INFISICAL_WEBHOOK_SECRETS": "a:"}, wantErr: "每项格式应为"},
+		{name: "missing id", values: map[string]string{"INFISICAL_WEBHOOK_SECRETS": ":topsecret"}, wantErr: "每项格式应为"},
+		{name: "no separator", values: map[string]string{"INFISICAL_WEBHOOK_SECRETS": "topsecret"}, wantErr: "每项格式应为"},
+		{name: "invalid expiry", values: map[string]string{"INFISICAL_WEBHOOK_SECRETS": "a:topsecret:tomorrow"}, wantErr: "a 的过期时间无效"},
+		{name: "duplicate id", values: map[string]string{"INFISICAL_WEBHOOK_SECRETS": "a:s1,a:s2"}, wantErr: "ID 重复: a"},
+		{
+			name:    "duplicate default id",
+			values:  map[string]string{"INFISICAL_WEBHOOK_SECRET": "s0", "INFISICAL_WEBHOOK_SECRETS": "default:s1"},
+			wantErr: "ID 重复: default",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := loadWebhookSecrets(newSource(tt.values), &cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadWebhookSecrets = %v, want error containing %q", err, tt.wantErr)
+				}
+				// 错误信息中不能出现 Secret
+				if strings.Contains(err.Error(), "topsecret") {
+					t.Fatalf("error %q leaks the secret", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadWebhookSecrets: %v", err)
+			}
+			if len(cfg.WebhookSecrets) != len(tt.want) {
+				t.Fatalf("secrets = %+v, want %+v", cfg.WebhookSecrets, tt.want)
+			}
+			for i, key := range cfg.WebhookSecrets {
+				want := tt.want[i]
+				if key.ID != want.ID || key.Secret != want.Secret || !key.ExpiresAt.Equal(want.ExpiresAt) {
+					t.Fatalf("secret %d = %+v, want %+v", i, key, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(newSource(nil))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.IsDevelopment() || cfg.BindAddr != ":8080" || cfg.MaxBodySize != defaultMaxBodySize ||
+		cfg.WebhookReplayWindow != signature.DefaultReplayWindow || cfg.WebhookReplayStore != ReplayStoreMemory ||
+		!cfg.DBAutoMigrate || !cfg.MetricsEnabled || cfg.AuthDisabled || cfg.OIDCEnabled() || cfg.TLSEnabled() {
+		t.Fatalf("Load() = %+v, want the defaults", cfg)
+	}
+	if !strings.HasPrefix(cfg.DBDSN, "sqlite://") {
+		t.Fatalf("DBDSN = %q, want the default SQLite path", cfg.DBDSN)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		env, value string
+	}{
+		{"APP_ENV", "staging"},
+		{"LOG_LEVEL", "verbose"},
+		{"TODO_BIND_ADDR", "localhost:http-alt-x"},
+		{"TODO_MAX_BODY_SIZE", "0"},
+		{"TODO_HTTP_READ_TIMEOUT", "-1s"},
+		{"TODO_SHUTDOWN_TIMEOUT", "0"},
+		{"TODO_DB_AUTO_MIGRATE", "maybe"},
+		{"TODO_DB_MAX_OPEN_CONNS", "many"},
+		{"WEBHOOK_REPLAY_WINDOW", "5"},
+		{"WEBHOOK_REPLAY_STORE", "redis"},
+		{"NOTIFY_MAX_ATTEMPTS", "0"},
+		{"METRICS_ENABLED", "yes please"},
+		{"OTEL_TRACES_EXPORTER", "jaeger"},
+		{"OTEL_TRACES_SAMPLER_ARG", "1.5"},
+		{"OTEL_EXPORTER_OTLP_HEADERS", "no-equals-sign"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(newSource(map[string]string{tt.env: tt.value})); err == nil || !strings.Contains(err.Error(), tt.env) {
+			t.Errorf("Load(%s=%q) = %v, want an error naming %s", tt.env, tt.value, err, tt.env)
+		}
+	}
+}
+
+func TestLoadTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	key := filepath.Join(dir, "key.pem")
+	for _, path := range []string{cert, key} {
+		if err := os.WriteFile(path, []byte("pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr string
+	}{
+		{name: "certificate files", values: map[string]string{"TODO_TLS_CERT_FILE": cert, "TODO_TLS_KEY_FILE": key}},
+		{name: "client ca", values: map[string]string{"TODO_TLS_CERT_FILE": cert, "TODO_TLS_KEY_FILE": key, "TODO_TLS_CLIENT_CA_FILE": cert}},
+		{name: "self-signed in development", values: map[string]string{"TODO_TLS_SELF_SIGNED": "true"}},
+		{name: "cert without key", values: map[string]string{"TODO_TLS_CERT_FILE": cert}, wantErr: "需要同时配置"},
+		{name: "missing file", values: map[string]string{"TODO_TLS_CERT_FILE": missing, "TODO_TLS_KEY_FILE": key}, wantErr: "TODO_TLS_CERT_FILE"},
+		{name: "self-signed with files", values: map[string]string{"TODO_TLS_SELF_SIGNED": "true", "TODO_TLS_CERT_FILE": cert, "TODO_TLS_KEY_FILE": key}, wantErr: "不能与"},
+		{name: "self-signed in production", values: map[string]string{"TODO_TLS_SELF_SIGNED": "true", "APP_ENV": "production"}, wantErr: "生产环境"},
+		{name: "client ca without https", values: map[string]string{"TODO_TLS_CLIENT_CA_FILE": cert}, wantErr: "启用 HTTPS"},
+		{name: "invalid reload interval", values: map[string]string{"TODO_TLS_RELOAD_INTERVAL": "0"}, wantErr: "TODO_TLS_RELOAD_INTERVAL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(newSource(tt.values))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !cfg.TLSEnabled() {
+				t.Fatalf("Load = TLS enabled %v, %v; want HTTPS enabled", cfg.TLSEnabled(), err)
+			}
+		})
+	}
+}
+
+func TestLoadOIDC(t *testing.T) {
+	base := map[string]string{
+		"OIDC_ISSUER_URL":   "https://id.example.com",
+		"OIDC_CLIENT_ID":    "todo",
+		"OIDC_REDIRECT_URL": "https://todo.example.com/auth/callback",
+		"SESSION_SECRET":    strings.Repeat("s", minSessionSecretLength),
+	}
+	with := func(overrides map[string]string) map[string]string {
+		values := map[string]string{}
+		for k, v := range base {
+			values[k] = v
+		}
+		for k, v := range overrides {
+			values[k] = v
+		}
+		return values
+	}
+
+	cfg, err := Load(newSource(base))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if strings.Join(cfg.OIDCScopes, " ") != "openid profile email" || cfg.OIDCDefaultRole != auth.RoleViewer ||
+		cfg.OIDCRoleClaim != defaultOIDCRoleClaim || cfg.SessionTTL != defaultSessionTTL || cfg.OIDCPostLoginRedirect != defaultPostLoginRedirect {
+		t.Fatalf("Load() = %+v, want the OIDC defaults", cfg)
+	}
+
+	cfg, err = Load(newSource(with(map[string]string{
+		"OIDC_SCOPES":          "groups, openid",
+		"OIDC_DEFAULT_ROLE":    "operator",
+		"OIDC_ADMIN_GROUPS":    "ops, sre",
+		"OIDC_OPERATOR_GROUPS": "dev",
+		"SESSION_TTL":          "1h",
+	})))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// openid 总是第一个 scope，且不会重复
+	if strings.Join(cfg.OIDCScopes, " ") != "openid groups" || cfg.OIDCDefaultRole != auth.RoleOperator ||
+		strings.Join(cfg.OIDCAdminGroups, ",") != "ops,sre" || strings.Join(cfg.OIDCOperatorGroups, ",") != "dev" || cfg.SessionTTL != time.Hour {
+		t.Fatalf("Load() = %+v, want the configured OIDC settings", cfg)
+	}
+
+	invalid := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{name: "missing client id", overrides: map[string]string{"OIDC_CLIENT_ID": ""}, wantErr: "OIDC_CLIENT_ID"},
+		{name: "short session secret", overrides: map[string]string{"SESSION_SECRET": "short"}, wantErr: "SESSION_SECRET"},
+		{name: "invalid default role", overrides: map[string]string{"OIDC_DEFAULT_ROLE": "root"}, wantErr: "OIDC_DEFAULT_ROLE"},
+		{name: "invalid session ttl", overrides: map[string]string{"SESSION_TTL": "forever"}, wantErr: "SESSION_TTL"},
+	}
+	for _, tt := range invalid {
+		if _, err := Load(newSource(with(tt.overrides))); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Load = %v, want error containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := map[string]string{
+		"LOG_LEVEL":                "info",
+		"INFISICAL_WEBHOOK_SECRET": "s0",
+		"APPRISE_URL":              "http://apprise:8000",
+		"NOTIFICATION_URLS":        "tgram://token/chat",
+	}
+	load := func(t *testing.T, overrides map[string]string) Config {
+		t.Helper()
+		values := map[string]string{}
+		for k, v := range base {
+			values[k] = v
+		}
+		for k, v := range overrides {
+			values[k] = v
+		}
+		cfg, err := Load(newSource(values))
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		return cfg
+	}
+
+	tests := []struct {
+		name                      string
+		overrides                 map[string]string
+		wantReloaded, wantRestart []string
+	}{
+		{name: "unchanged"},
+		{
+			name:         "reloadable settings",
+			overrides:    map[string]string{"LOG_LEVEL": "debug", "INFISICAL_WEBHOOK_SECRETS": "next:s1", "CORS_ALLOWED_ORIGINS": "https://app.example.com", "NOTIFICATION_URLS": "mailto://ops"},
+			wantReloaded: []string{"log.level", "webhook.secrets", "cors.allowed_origins", "notify.urls"},
+		},
+		{
+			name:        "restart-required settings",
+			overrides:   map[string]string{"TODO_BIND_ADDR": ":9090", "TODO_DB_DSN": "sqlite:///tmp/other.db", "METRICS_ENABLED": "false"},
+			wantRestart: []string{"server.bind_addr", "database.dsn", "metrics.enabled"},
+		},
+		{
+			// 关闭推送通知需要停止发件箱 Worker，因此即使 notify.* 可以重新加载也需要重启
+			name:         "notify disabled",
+			overrides:    map[string]string{"NOTIFICATION_URLS": "", "LOG_LEVEL": "warn"},
+			wantReloaded: []string{"log.level"},
+			wantRestart:  []string{"notify.urls"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := load(t, nil)
+			next := load(t, tt.overrides)
+			reloaded, restart := current.Diff(next)
+			if strings.Join(reloaded, ",") != strings.Join(tt.wantReloaded, ",") || strings.Join(restart, ",") != strings.Join(tt.wantRestart, ",") {
+				t.Fatalf("Diff = %v, %v; want %v, %v", reloaded, restart, tt.wantReloaded, tt.wantRestart)
+			}
+
+			// ApplyReloadable 只复制可以重新加载的配置项
+			current.ApplyReloadable(next)
+			if again, _ := current.Diff(next); len(again) != 0 {
+				t.Fatalf("Diff after ApplyReloadable = %v, want no reloadable changes", again)
+			}
+			if current.BindAddr != ":8080" {
+				t.Fatalf("ApplyReloadable changed restart-required settings: %+v", current)
+			}
+		})
+	}
+
+	// 由关闭变为启用同样需要重启，此时不替换推送目标
+	current := load(t, map[string]string{"APPRISE_URL": ""})
+	next := load(t, nil)
+	if _, restart := current.Diff(next); strings.Join(restart, ",") != "notify.apprise_url" {
+		t.Fatalf("Diff when enabling notifications = %v, want notify.apprise_url to require a restart", restart)
+	}
+	current.ApplyReloadable(next)
+	if current.NotificationEnabled() {
+		t.Fatal("ApplyReloadable enabled notifications, want them to stay disabled until restart")
+	}
+}
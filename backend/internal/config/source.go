@@ -0,0 +1,433 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// configFileEnv 指定配置文件路径的环境变量，命令行参数 --config 优先。
+const configFileEnv = "TODO_CONFIG_FILE"
+
+// 配置值的来源，按优先级从低到高排列。config print 会在每一项后面注明来源。
+const (
+	originDefault = "default"
+	originFile    = "file"
+	originEnv     = "env"
+	originFlag    = "flag"
+)
+
+// settingKind 描述配置项在配置文件中的取值形式。
+type settingKind int
+
+const (
+	kindScalar settingKind = iota // 单个值：字符串、数字、布尔值或时长
+	kindList                      // 列表，对应环境变量中用逗号分隔的多个值
+	kindMap                       // 键值对，对应环境变量中的 key1=value1,key2=value2
+)
+
+// setting 描述一个配置项在配置文件、环境变量与命令行参数中的名称。
+// 三种来源最终都归一为环境变量格式的字符串，再由 Load 统一解析与校验，
+// 这样每个配置项只有一份解析逻辑，无论它来自哪里，校验规则都相同。
+type setting struct {
+	// key 是配置文件中的键（以 . 分隔层级），同时也是命令行参数名，例如 server.bind_addr。
+	key string
+
+	// env 是对应的环境变量名。
+	env string
+
+	kind  settingKind
+	usage string
+
+	// value 从加载后的 Config 中取出生效的值，供 config print 输出。
+	value func(*Config) any
+
+	// redact 为 config print --redacted 隐藏敏感信息，nil 表示不是敏感信息。
+	redact func(any) any
+
+	// reloadable 表示该配置项可以在运行时重新加载，不需要重启服务。
+	// 新增可重新加载的配置项时，还需要在 Config.ApplyReloadable 中复制对应的字段。
+	reloadable bool
+}
+
+// settings 列出所有配置项，顺序即 config print 的输出顺序。
+// 新增配置项时需要在这里登记，否则配置文件中的同名键会被当作未知配置项拒绝。
+var settings = []setting{
+	{key: "app_env", env: "APP_ENV", usage: "运行环境：development 或 production",
+		value: func(c *Config) any { return c.Environment }},
+
+	{key: "log.format", env: "LOG_FORMAT", usage: "日志格式：text 或 json",
+		value: func(c *Config) any { return c.LogFormat }},
+	{key: "log.level", env: "LOG_LEVEL", usage: "日志级别：debug、info、warn 或 error",
+		value: func(c *Config) any { return strings.ToLower(c.LogLevel.String()) }, reloadable: true},
+
+	{key: "server.bind_addr", env: "TODO_BIND_ADDR", usage: "HTTP 服务监听地址，例如 :8080",
+		value: func(c *Config) any { return c.BindAddr }},
+	{key: "server.max_body_size", env: "TODO_MAX_BODY_SIZE", usage: "请求体最大大小（字节）",
+		value: func(c *Config) any { return c.MaxBodySize }},
+	{key: "server.read_header_timeout", env: "TODO_HTTP_READ_HEADER_TIMEOUT", usage: "读取请求头的超时时间",
+		value: func(c *Config) any { return c.HTTPReadHeaderTimeout }},
+	{key: "server.read_timeout", env: "TODO_HTTP_READ_TIMEOUT", usage: "读取整个请求的超时时间",
+		value: func(c *Config) any { return c.HTTPReadTimeout }},
+	{key: "server.write_timeout", env: "TODO_HTTP_WRITE_TIMEOUT", usage: "写入响应的超时时间",
+		value: func(c *Config) any { return c.HTTPWriteTimeout }},
+	{key: "server.idle_timeout", env: "TODO_HTTP_IDLE_TIMEOUT", usage: "Keep-Alive 空闲连接的超时时间",
+		value: func(c *Config) any { return c.HTTPIdleTimeout }},
+	{key: "server.shutdown_timeout", env: "TODO_SHUTDOWN_TIMEOUT", usage: "收到退出信号后等待进行中请求完成的最长时间",
+		value: func(c *Config) any { return c.ShutdownTimeout }},
+
+	{key: "reload.watch_interval", env: "TODO_CONFIG_WATCH_INTERVAL", usage: "检查配置文件是否变化的间隔，0 表示不监听",
+		value: func(c *Config) any { return c.ConfigWatchInterval }},
+
+	{key: "tls.cert_file", env: "TODO_TLS_CERT_FILE", usage: "HTTPS 证书文件（PEM）",
+		value: func(c *Config) any { return c.TLSCertFile }},
+	{key: "tls.key_file", env: "TODO_TLS_KEY_FILE", usage: "HTTPS 私钥文件（PEM）",
+		value: func(c *Config) any { return c.TLSKeyFile }},
+	{key: "tls.reload_interval", env: "TODO_TLS_RELOAD_INTERVAL", usage: "检查证书文件是否更新的间隔",
+		value: func(c *Config) any { return c.TLSReloadInterval }},
+	{key: "tls.self_signed", env: "TODO_TLS_SELF_SIGNED", usage: "使用自签名证书提供 HTTPS（仅限开发环境）",
+		value: func(c *Config) any { return c.TLSSelfSigned }},
+	{key: "tls.client_ca_file", env: "TODO_TLS_CLIENT_CA_FILE", usage: "客户端证书的 CA（PEM），配置后 API 要求 mTLS",
+		value: func(c *Config) any { return c.TLSClientCAFile }},
+
+	{key: "database.dsn", env: "TODO_DB_DSN", usage: "数据库连接串，sqlite:// 或 postgres:// 前缀",
+		value: func(c *Config) any { return c.DBDSN }, redact: redactDSN},
+	{key: "database.path", env: "TODO_DB_PATH", usage: "SQLite 数据库文件路径（未设置 database.dsn 时使用）",
+		value: func(c *Config) any { return c.DBPath }},
+	{key: "database.max_open_conns", env: "TODO_DB_MAX_OPEN_CONNS", usage: "PostgreSQL 最大连接数",
+		value: func(c *Config) any { return c.DBMaxOpenConns }},
+	{key: "database.auto_migrate", env: "TODO_DB_AUTO_MIGRATE", usage: "启动时自动执行数据库迁移",
+		value: func(c *Config) any { return c.DBAutoMigrate }},
+
+	{key: "webhook.secret", env: "INFISICAL_WEBHOOK_SECRET", usage: "Infisical Webhook 签名密钥",
+		value: defaultWebhookSecret, redact: redactAll, reloadable: true},
+	{key: "webhook.secrets", env: "INFISICAL_WEBHOOK_SECRETS", kind: kindList, usage: "多个 Webhook Secret，每项格式 id:secret[:过期时间]",
+		value: extraWebhookSecrets, redact: redactWebhookSecrets, reloadable: true},
+	{key: "webhook.replay_window", env: "WEBHOOK_REPLAY_WINDOW", usage: "Webhook 签名时间戳的有效窗口",
+		value: func(c *Config) any { return c.WebhookReplayWindow }},
+	{key: "webhook.replay_store", env: "WEBHOOK_REPLAY_STORE", usage: "重放缓存的存储方式：memory 或 db",
+		value: func(c *Config) any { return c.WebhookReplayStore }},
+
+	{key: "cors.allowed_origins", env: "CORS_ALLOWED_ORIGINS", kind: kindList, usage: "允许的跨域来源",
+		value: func(c *Config) any { return c.CORSAllowedOrigins }, reloadable: true},
+
+	{key: "notify.apprise_url", env: "APPRISE_URL", usage: "Apprise API 地址",
+		value: func(c *Config) any { return c.AppriseURL }, reloadable: true},
+	{key: "notify.urls", env: "NOTIFICATION_URLS", usage: "Apprise 目标 URL 列表",
+		value: func(c *Config) any { return c.NotificationURLs }, redact: redactAll, reloadable: true},
+	{key: "notify.max_attempts", env: "NOTIFY_MAX_ATTEMPTS", usage: "单条通知的最大发送次数",
+		value: func(c *Config) any { return c.NotifyMaxAttempts }},
+
+	{key: "metrics.enabled", env: "METRICS_ENABLED", usage: "是否在 /metrics 暴露 Prometheus 指标",
+		value: func(c *Config) any { return c.MetricsEnabled }},
+
+	{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", usage: "链路追踪导出方式：none、otlp 或 noop",
+		value: func(c *Config) any { return c.TracingExporter }},
+	{key: "tracing.endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", usage: "OTLP/HTTP 接收地址",
+		value: func(c *Config) any { return c.TracingEndpoint }},
+	{key: "tracing.headers", env: "OTEL_EXPORTER_OTLP_HEADERS", kind: kindMap, usage: "发送给 OTLP 接收端的请求头",
+		value: func(c *Config) any { return c.TracingHeaders }, redact: redactMapValues},
+	{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", usage: "上报的服务名",
+		value: func(c *Config) any { return c.TracingServiceName }},
+	{key: "tracing.sample_ratio", env: "OTEL_TRACES_SAMPLER_ARG", usage: "采样比例（0 ~ 1）",
+		value: func(c *Config) any { return c.TracingSampleRatio }},
+
+	{key: "auth.admin_token", env: "TODO_ADMIN_TOKEN", usage: "静态管理 Token，用于首次创建 API Token",
+		value: func(c *Config) any { return c.AdminToken }, redact: redactAll},
+	{key: "auth.disabled", env: "TODO_AUTH_DISABLED", usage: "关闭 API 认证（仅限已有 VPN/代理保护的部署）",
+		value: func(c *Config) any { return c.AuthDisabled }},
+
+	{key: "oidc.issuer_url", env: "OIDC_ISSUER_URL", usage: "OIDC 身份提供方 Issuer 地址",
+		value: func(c *Config) any { return c.OIDCIssuerURL }},
+	{key: "oidc.client_id", env: "OIDC_CLIENT_ID", usage: "OIDC 客户端 ID",
+		value: func(c *Config) any { return c.OIDCClientID }},
+	{key: "oidc.client_secret", env: "OIDC_CLIENT_SECRET", usage: "OIDC 客户端密钥",
+		value: func(c *Config) any { return c.OIDCClientSecret }, redact: redactAll},
+	{key: "oidc.redirect_url", env: "OIDC_REDIRECT_URL", usage: "OIDC 回调地址",
+		value: func(c *Config) any { return c.OIDCRedirectURL }},
+	{key: "oidc.scopes", env: "OIDC_SCOPES", kind: kindList, usage: "请求的 scope",
+		value: func(c *Config) any { return c.OIDCScopes }},
+	{key: "oidc.post_login_redirect", env: "OIDC_POST_LOGIN_REDIRECT", usage: "登录/登出完成后跳转的地址",
+		value: func(c *Config) any { return c.OIDCPostLoginRedirect }},
+	{key: "oidc.role_claim", env: "OIDC_ROLE_CLAIM", usage: "ID Token 中保存用户组的 claim",
+		value: func(c *Config) any { return c.OIDCRoleClaim }},
+	{key: "oidc.admin_groups", env: "OIDC_ADMIN_GROUPS", kind: kindList, usage: "映射为 admin 角色的用户组",
+		value: func(c *Config) any { return c.OIDCAdminGroups }},
+	{key: "oidc.operator_groups", env: "OIDC_OPERATOR_GROUPS", kind: kindList, usage: "映射为 operator 角色的用户组",
+		value: func(c *Config) any { return c.OIDCOperatorGroups }},
+	{key: "oidc.default_role", env: "OIDC_DEFAULT_ROLE", usage: "未匹配任何用户组时的角色",
+		value: func(c *Config) any { return string(c.OIDCDefaultRole) }},
+
+	{key: "session.secret", env: "SESSION_SECRET", usage: "会话 Cookie 签名密钥",
+		value: func(c *Config) any { return c.SessionSecret }, redact: redactAll},
+	{key: "session.ttl", env: "SESSION_TTL", usage: "登录会话有效期",
+		value: func(c *Config) any { return c.SessionTTL }},
+}
+
+// Source 是合并后的原始配置，按 配置文件 → 环境变量 → 命令行参数 的顺序覆盖。
+// 所有值都以环境变量的格式保存，由 Load 解析与校验。
+type Source struct {
+	// File 是读取的配置文件路径，没有使用配置文件时为空。
+	File string
+
+	values  map[string]string // 环境变量名 → 值
+	origins map[string]string // 环境变量名 → 来源
+}
+
+// ParseArgs 解析命令行参数并读取配置文件，返回合并后的配置来源与剩余的参数（子命令）。
+// 参数需要写在子命令之前，例如：backend --config config.yaml --server.bind_addr :9090 migrate up。
+// 环境变量为空字符串时视为未设置，不会覆盖配置文件中的值。
+func ParseArgs(args []string) (*Source, []string, error) {
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := fs.String("config", "", "配置文件路径（.yaml、.yml 或 .toml），也可以通过 "+configFileEnv+" 指定")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = fs.String(s.key, "", s.usage+"（环境变量 "+s.env+"）")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	src := &Source{
+		values:  make(map[string]string, len(settings)),
+		origins: make(map[string]string, len(settings)),
+	}
+
+	src.File = strings.TrimSpace(*configFile)
+	if src.File == "" {
+		src.File = strings.TrimSpace(os.Getenv(configFileEnv))
+	}
+	if src.File != "" {
+		values, err := readFile(src.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		for env, value := range values {
+			src.set(env, value, originFile)
+		}
+	}
+
+	for _, s := range settings {
+		if value := strings.TrimSpace(os.Getenv(s.env)); value != "" {
+			src.set(s.env, value, originEnv)
+		}
+	}
+
+	// 只覆盖显式传入的参数，传入空字符串表示恢复默认值
+	fs.Visit(func(f *flag.Flag) {
+		if s, ok := lookupSetting(f.Name); ok {
+			src.set(s.env, strings.TrimSpace(*flagValues[s.key]), originFlag)
+		}
+	})
+	return src, fs.Args(), nil
+}
+
+func (s *Source) set(env, value, origin string) {
+	s.values[env] = value
+	s.origins[env] = origin
+}
+
+// get 返回配置项的值，未设置时返回空字符串。
+func (s *Source) get(env string) string {
+	return s.values[env]
+}
+
+// origin 返回配置项的来源。
+func (s *Source) origin(env string) string {
+	if origin, ok := s.origins[env]; ok {
+		return origin
+	}
+	return originDefault
+}
+
+// label 返回错误信息中配置项的名称：按值的来源使用配置文件的键、命令行参数或环境变量名，
+// 方便用户找到需要修改的位置。
+func (s *Source) label(env string) string {
+	setting, ok := lookupEnv(env)
+	if !ok {
+		return env
+	}
+	switch s.origin(env) {
+	case originFile:
+		return fmt.Sprintf("配置文件中的 %s", setting.key)
+	case originFlag:
+		return "--" + setting.key
+	default:
+		return env
+	}
+}
+
+// lookupSetting 按配置文件的键查找配置项。
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// lookupEnv 按环境变量名查找配置项。
+func lookupEnv(env string) (setting, bool) {
+	for _, s := range settings {
+		if s.env == env {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// readFile 读取 YAML 或 TOML 配置文件，返回 环境变量名 → 值。
+// 未知的键、类型不符的值都会返回错误，避免拼写错误的配置项被悄悄忽略。
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式 %q（可选 .yaml、.yml 或 .toml）", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	var problems []string
+	flattenFile("", raw, values, &problems)
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("配置文件 %s 无效:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	return values, nil
+}
+
+// flattenFile 把配置文件的层级结构展开为 环境变量名 → 值，问题记录到 problems 中。
+func flattenFile(prefix string, node map[string]any, values map[string]string, problems *[]string) {
+	for name, raw := range node {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		s, ok := lookupSetting(key)
+		if !ok {
+			// 不是配置项的键只能是分组，例如 server、tls
+			if child, isMap := raw.(map[string]any); isMap && isSection(key) {
+				flattenFile(key, child, values, problems)
+			} else {
+				*problems = append(*problems, fmt.Sprintf("%s: 未知的配置项", key))
+			}
+			continue
+		}
+
+		value, err := fileValue(s, raw)
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if value != "" {
+			values[s.env] = value
+		}
+	}
+}
+
+// isSection 判断 key 是否是某些配置项的分组。
+func isSection(key string) bool {
+	return slices.ContainsFunc(settings, func(s setting) bool {
+		return strings.HasPrefix(s.key, key+".")
+	})
+}
+
+// fileValue 把配置文件中的值转换为环境变量格式的字符串。
+func fileValue(s setting, raw any) (string, error) {
+	if raw == nil {
+		return "", nil
+	}
+
+	switch s.kind {
+	case kindList:
+		items, ok := raw.([]any)
+		if !ok {
+			// 也允许与环境变量一样写成逗号分隔的字符串
+			return scalarValue(raw)
+		}
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			value, err := scalarValue(item)
+			if err != nil {
+				return "", fmt.Errorf("列表中的每一项都应为单个值")
+			}
+			if strings.Contains(value, ",") {
+				return "", fmt.Errorf("列表项不能包含逗号: %q", value)
+			}
+			parts = append(parts, value)
+		}
+		return strings.Join(parts, ","), nil
+
+	case kindMap:
+		entries, ok := raw.(map[string]any)
+		if !ok {
+			return scalarValue(raw)
+		}
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			value, err := scalarValue(entries[key])
+			if err != nil {
+				return "", fmt.Errorf("%s 的值应为单个值", key)
+			}
+			parts = append(parts, key+"="+url.PathEscape(value))
+		}
+		return strings.Join(parts, ","), nil
+
+	default:
+		return scalarValue(raw)
+	}
+}
+
+// scalarValue 把单个值转换为字符串。
+func scalarValue(raw any) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return strings.TrimSpace(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case fmt.Stringer:
+		// TOML 的本地日期、时间类型
+		return v.String(), nil
+	default:
+		return "", errors.New("应为单个值（字符串、数字或布尔值）")
+	}
+}
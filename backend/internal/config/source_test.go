@@ -0,0 +1,136 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile 在临时目录中写入配置文件，返回它的路径。
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseArgsPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+log:
+  level: debug
+server:
+  bind_addr: ":7000"
+  max_body_size: 1024
+webhook:
+  secrets: ["a:s1", "b:s2:2030-01-01"]
+cors:
+  allowed_origins: [https://file.example.com]
+tracing:
+  headers:
+    x-api-key: k
+`)
+	// 环境变量覆盖配置文件，命令行参数覆盖环境变量；空的环境变量视为未设置
+	t.Setenv("TODO_BIND_ADDR", ":8000")
+	t.Setenv("TODO_MAX_BODY_SIZE", "2048")
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "")
+
+	src, rest, err := ParseArgs([]string{"--config", path, "--server.bind_addr", ":9000", "--log.format=json", "migrate", "up"})
+	if err != nil {
+		t.Fatalf("ParseArgs: %v", err)
+	}
+	if src.File != path || strings.Join(rest, " ") != "migrate up" {
+		t.Fatalf("ParseArgs = file %q, rest %q", src.File, rest)
+	}
+
+	origins := map[string]string{
+		"LOG_LEVEL":                  originFile,
+		"LOG_FORMAT":                 originFlag,
+		"TODO_BIND_ADDR":             originFlag,
+		"TODO_MAX_BODY_SIZE":         originEnv,
+		"CORS_ALLOWED_ORIGINS":       originFile,
+		"TODO_SHUTDOWN_TIMEOUT":      originDefault,
+		"INFISICAL_WEBHOOK_SECRETS":  originFile,
+		"OTEL_EXPORTER_OTLP_HEADERS": originFile,
+	}
+	for env, want := range origins {
+		if got := src.origin(env); got != want {
+			t.Errorf("origin(%s) = %s, want %s", env, got, want)
+		}
+	}
+
+	cfg, err := Load(src)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.LogLevel != slog.LevelDebug || cfg.LogFormat != "json" || cfg.BindAddr != ":9000" || cfg.MaxBodySize != 2048 ||
+		strings.Join(cfg.CORSAllowedOrigins, ",") != "https://file.example.com" || cfg.ShutdownTimeout != defaultShutdownTimeout ||
+		len(cfg.WebhookSecrets) != 2 || !cfg.WebhookSecrets[1].ExpiresAt.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		cfg.TracingHeaders["x-api-key"] != "k" {
+		t.Fatalf("Load() = %+v, want the merged configuration", cfg)
+	}
+
+	// TODO_CONFIG_FILE 指定配置文件，显式传入空的参数恢复默认值
+	t.Setenv(configFileEnv, path)
+	src, _, err = ParseArgs([]string{"--server.bind_addr="})
+	if err != nil {
+		t.Fatalf("ParseArgs: %v", err)
+	}
+	if cfg, err := Load(src); err != nil || src.File != path || cfg.BindAddr != ":8080" || cfg.MaxBodySize != 2048 {
+		t.Fatalf("Load() = %+v, %v; want the default bind address", cfg, err)
+	}
+}
+
+func TestParseArgsTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+app_env = "production"
+
+[database]
+max_open_conns = 20
+auto_migrate = false
+`)
+	src, _, err := ParseArgs([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("ParseArgs: %v", err)
+	}
+	cfg, err := Load(src)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.IsDevelopment() || cfg.DBMaxOpenConns != 20 || cfg.DBAutoMigrate {
+		t.Fatalf("Load() = %+v, want the TOML values", cfg)
+	}
+}
+
+func TestParseArgsRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name, file, content, wantErr string
+	}{
+		{name: "unknown key", file: "config.yaml", content: "server:\n  bind_adr: \":9000\"\n", wantErr: "server.bind_adr"},
+		{name: "unknown section", file: "config.yaml", content: "cache:\n  size: 1\n", wantErr: "cache"},
+		{name: "list for scalar", file: "config.yaml", content: "server:\n  bind_addr: [a, b]\n", wantErr: "server.bind_addr"},
+		{name: "comma in list item", file: "config.yaml", content: "cors:\n  allowed_origins: [\"a,b\"]\n", wantErr: "逗号"},
+		{name: "invalid yaml", file: "config.yaml", content: "server: [\n", wantErr: "解析配置文件"},
+		{name: "unsupported format", file: "config.json", content: "{}", wantErr: "不支持的配置文件格式"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+			if _, _, err := ParseArgs([]string{"--config", path}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseArgs = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, _, err := ParseArgs([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatal("ParseArgs with a missing file succeeded, want an error")
+	}
+	if _, _, err := ParseArgs([]string{"--no-such-flag", "x"}); err == nil {
+		t.Fatal("ParseArgs with an unknown flag succeeded, want an error")
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// defaultPostgresMaxOpenConns 是 PostgreSQL 默认的最大连接数。
+const defaultPostgresMaxOpenConns = 10
+
+// openPostgres 连接 PostgreSQL 并配置连接池。
+// 与 SQLite 不同，PostgreSQL 支持并发写入，请求之间不会互相阻塞。
+func openPostgres(dsn string, opts Options) (*gorm.DB, error) {
+	// postgres.Open 使用 pgx 驱动，可以直接接受 postgres:// 形式的 URL。
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	maxOpen := opts.MaxOpenConns
+	if maxOpen <= 0 {
+		maxOpen = defaultPostgresMaxOpenConns
+	}
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxOpen)
+
+	// 定期回收连接，避免连接被中间的负载均衡或 PgBouncer 静默断开。
+	sqlDB.SetConnMaxLifetime(30 * time.Minute)
+
+	return db, nil
+}
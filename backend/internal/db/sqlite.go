@@ -1,5 +1,3 @@
-// Package db 负责数据库的底层连接和配置。
-// 这里封装了 GORM 对 SQLite 的初始化逻辑。
 package db
 
 import (
@@ -11,12 +9,32 @@ import (
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	_ "modernc.org/sqlite" // 使用纯 Go 的 SQLite 驱动 (无需 CGO)
+	moderncsqlite "modernc.org/sqlite" // 使用纯 Go 的 SQLite 驱动 (无需 CGO)
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
-// Open 初始化并返回一个 GORM 数据库连接实例。
+// sqliteDialector 在 GORM SQLite 方言的基础上补充 modernc 驱动的错误翻译。
+// 上游的 Translate 按 mattn/go-sqlite3 的错误结构解析，识别不了 modernc 的 *sqlite.Error，
+// 导致唯一索引冲突无法被翻译为 gorm.ErrDuplicatedKey。
+type sqliteDialector struct {
+	sqlite.Dialector
+}
+
+// Translate 将 modernc 的约束冲突错误翻译为 GORM 标准错误，其余交给上游处理。
+func (d sqliteDialector) Translate(err error) error {
+	var sqliteErr *moderncsqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	return d.Dialector.Translate(err)
+}
+
+// openSQLite 初始化 SQLite 数据库连接。
 // 它会自动创建数据库文件所在的目录，并配置连接池参数。
-func Open(path string) (*gorm.DB, error) {
+func openSQLite(path string) (*gorm.DB, error) {
 	if strings.TrimSpace(path) == "" {
 		return nil, errors.New("empty sqlite path")
 	}
@@ -34,13 +52,11 @@ func Open(path string) (*gorm.DB, error) {
 	// 2. 打开数据库连接
 	// gorm.Open 是 GORM 的入口方法。
 	// 使用 sqlite.Dialector 显式指定使用 modernc.org/sqlite 驱动（纯 Go，无需 CGO）。
-	// &gorm.Config{} 可以传递高级配置，这里使用默认配置。
-	db, err := gorm.Open(sqlite.Dialector{
+	// gormConfig 中的配置与 PostgreSQL 共用。
+	db, err := gorm.Open(sqliteDialector{sqlite.Dialector{
 		DriverName: "sqlite", // modernc.org/sqlite 注册的驱动名
 		DSN:        path,
-	}, &gorm.Config{
-		TranslateError: true, // 将数据库驱动的原始错误翻译为 GORM 标准错误（如 ErrDuplicatedKey）
-	})
+	}}, gormConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +71,7 @@ func Open(path string) (*gorm.DB, error) {
 	// SQLite 是文件型数据库，默认只支持单写。
 	// 为了避免 "database is locked" 错误，我们将最大打开连接数限制为 1。
 	// 这意味着所有写操作会串行执行，读操作也会受到影响，但保证了数据安全性。
-	// 对于高并发场景，通常会切换到 PostgreSQL 或 MySQL。
+	// 对于高并发场景，可以通过 postgres:// DSN 切换到 PostgreSQL。
 	sqlDB.SetMaxOpenConns(1)
 
 	// 设置最大空闲连接数，保持 1 个连接常驻，避免频繁打开/关闭文件。
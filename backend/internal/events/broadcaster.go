@@ -0,0 +1,184 @@
+// Package events 提供进程内的待办事项变更广播。
+// Handler 在修改待办事项后发布事件，SSE 等实时推送接口订阅这些事件，
+// 让前端无需轮询即可感知 Webhook 创建或重置了待办事项。
+package events
+
+import (
+	"sync"
+	"time"
+
+	"backend/internal/models"
+)
+
+// Type 是事件类型，同时用作 SSE 的 event 名称。
+type Type string
+
+const (
+	TodoCreated   Type = "todo.created"
+	TodoReset     Type = "todo.reset"
+	TodoCompleted Type = "todo.completed"
+	TodoReopened  Type = "todo.reopened"
+	TodoDeleted   Type = "todo.deleted"
+)
+
+// 广播器的默认参数。
+const (
+	// DefaultHistorySize 是保留的历史事件数量，用于断线重连后补发。
+	DefaultHistorySize = 256
+
+	// subscriberBuffer 是每个订阅者的缓冲区大小。
+	// 缓冲区写满说明订阅者消费过慢，会被断开，由客户端携带 Last-Event-ID 重连补发。
+	subscriberBuffer = 64
+)
+
+// Event 是一次待办事项变更。
+type Event struct {
+	// ID 单调递增，可作为 SSE 的 Last-Event-ID。
+	ID uint64
+	// Type 是变更类型。
+	Type Type
+	// Todo 是变更后的待办事项；删除事件中为删除前的记录。
+	Todo models.TodoItem
+	// At 是事件发布时间。
+	At time.Time
+}
+
+// Subscription 是一个订阅者。
+// 通过 Events 接收事件；通道被关闭说明订阅者消费过慢已被断开，或已调用 Close。
+type Subscription struct {
+	ch     chan Event
+	broker *Broadcaster
+}
+
+// Events 返回接收事件的通道。
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close 取消订阅。可以重复调用。
+func (s *Subscription) Close() {
+	s.broker.remove(s.ch)
+}
+
+// Broadcaster 将事件分发给所有订阅者，并保留最近的事件用于断线补发。
+// 可以被多个 goroutine 并发使用。
+type Broadcaster struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+// NewBroadcaster 创建 Broadcaster 实例，historySize <= 0 时使用默认值。
+// 事件 ID 从启动时的微秒时间戳开始递增，因此重启后的新 ID 总是大于旧进程的 ID，
+// 携带旧 ID 重连的客户端会被识别为无法补发，而不会误收到错位的事件。
+func NewBroadcaster(historySize int) *Broadcaster {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Broadcaster{
+		lastID:      uint64(time.Now().UnixMicro()),
+		historySize: historySize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish 发布一个事件，并返回分配了 ID 的事件。
+// 发布不会阻塞：缓冲区已满的订阅者会被断开。
+func (b *Broadcaster) Publish(eventType Type, todo models.TodoItem) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Todo: todo, At: time.Now().UTC()}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return event
+}
+
+// Subscribe 注册一个订阅者。
+// afterID > 0 时同时返回历史中 ID 大于 afterID 的事件（backlog）。
+// complete 为 false 表示 afterID 之后的事件已经不在历史中（例如断线太久或服务已重启），
+// 此时不返回 backlog，调用方应提示客户端重新拉取完整列表。
+func (b *Broadcaster) Subscribe(afterID uint64) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	sub = &Subscription{ch: ch, broker: b}
+	if b.closed {
+		// 服务正在关闭，返回一个已关闭的通道，调用方会像被断开一样立即结束
+		close(ch)
+		return sub, nil, true
+	}
+	b.subscribers[ch] = struct{}{}
+
+	if afterID == 0 {
+		return sub, nil, true
+	}
+	if afterID > b.lastID {
+		return sub, nil, false
+	}
+
+	// 历史中最早的事件之前还有未补发的事件，说明无法完整补发，
+	// 此时客户端需要重新拉取完整列表，补发部分事件没有意义
+	if afterID != b.lastID && (len(b.history) == 0 || b.history[0].ID > afterID+1) {
+		return sub, nil, false
+	}
+	for _, event := range b.history {
+		if event.ID > afterID {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, true
+}
+
+// Close 断开所有订阅者，之后的订阅会立即被断开。
+// 服务关闭时调用，让 SSE、WebSocket 等长连接结束，客户端会重连到新的实例。
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Closed 报告 Close 是否已被调用，用于区分订阅者是因为服务关闭还是消费过慢被断开。
+func (b *Broadcaster) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// LastID 返回最近一次发布的事件 ID。
+func (b *Broadcaster) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// remove 移除订阅者并关闭其通道。
+func (b *Broadcaster) remove(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
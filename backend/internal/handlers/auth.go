@@ -0,0 +1,215 @@
+// Package handlers 包含 OIDC 登录相关的处理逻辑。
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// stateCookieName 保存 OIDC 登录过程中的 state/nonce/PKCE verifier。
+	stateCookieName = "itn_oidc_state"
+	// stateCookiePath 限制 state Cookie 只在 /auth 下发送。
+	stateCookiePath = "/auth"
+	// loginStateTTL 是从跳转登录到回调之间允许的最长时间。
+	loginStateTTL = 10 * time.Minute
+)
+
+// loginState 是保存在签名 Cookie 中的登录中间状态。
+type loginState struct {
+	State     string `json:"state"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	Redirect  string `json:"redirect"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// AuthHandler 处理 OIDC 授权码登录、回调、登出以及当前用户查询。
+type AuthHandler struct {
+	client       *auth.OIDCClient // 未启用 OIDC 时为 nil
+	signer       *auth.Signer
+	sessionTTL   time.Duration
+	secureCookie bool   // 是否为 Cookie 设置 Secure 属性（仅 HTTPS 发送）
+	redirectURL  string // 登录或登出完成后的默认跳转地址
+}
+
+// NewAuthHandler 创建 AuthHandler 实例。
+func NewAuthHandler(client *auth.OIDCClient, signer *auth.Signer, sessionTTL time.Duration, secureCookie bool, redirectURL string) *AuthHandler {
+	return &AuthHandler{
+		client:       client,
+		signer:       signer,
+		sessionTTL:   sessionTTL,
+		secureCookie: secureCookie,
+		redirectURL:  redirectURL,
+	}
+}
+
+// Login 跳转到身份提供方的登录页。
+// 可选的 redirect 查询参数指定登录成功后返回的站内路径。
+//
+//	@Summary		OIDC 登录
+//	@Description	生成 state、nonce 与 PKCE verifier 并跳转到身份提供方登录页
+//	@Tags			auth
+//	@Param			redirect	query	string	false	"登录成功后跳转的站内路径，例如 /tasks"
+//	@Success		302
+//	@Failure		502	{object}	map[string]string	"身份提供方不可用"
+//	@Router			/auth/login [get]
+func (h *AuthHandler) Login(c *gin.Context) {
+	state := loginState{
+		State:     randomString(),
+		Nonce:     randomString(),
+		Verifier:  oauth2.GenerateVerifier(),
+		Redirect:  safeRedirect(c.Query("redirect")),
+		ExpiresAt: time.Now().Add(loginStateTTL).Unix(),
+	}
+
+	target, err := h.client.AuthCodeURL(c.Request.Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "获取 OIDC Provider 配置失败", "error", err)
+		RespondError(c, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	value, err := h.signer.Sign(state)
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "create login state failed")
+		return
+	}
+	h.setCookie(c, stateCookieName, value, stateCookiePath, int(loginStateTTL.Seconds()))
+
+	c.Redirect(http.StatusFound, target)
+}
+
+// Callback 处理身份提供方的回调：校验 state，换取并校验 ID Token，然后签发会话 Cookie。
+//
+//	@Summary		OIDC 回调
+//	@Description	校验 state 与 ID Token，签发会话 Cookie 后跳转回前端
+//	@Tags			auth
+//	@Param			code	query	string	true	"授权码"
+//	@Param			state	query	string	true	"登录时生成的 state"
+//	@Success		302
+//	@Failure		400	{object}	map[string]string	"登录状态无效或已过期"
+//	@Failure		401	{object}	map[string]string	"身份校验失败"
+//	@Router			/auth/callback [get]
+func (h *AuthHandler) Callback(c *gin.Context) {
+	// state Cookie 只能使用一次
+	cookieValue, _ := c.Cookie(stateCookieName)
+	h.setCookie(c, stateCookieName, "", stateCookiePath, -1)
+
+	if errParam := c.Query("error"); errParam != "" {
+		slog.WarnContext(c.Request.Context(), "身份提供方返回错误", "error", errParam, "description", c.Query("error_description"))
+		RespondError(c, http.StatusUnauthorized, "login failed")
+		return
+	}
+
+	var state loginState
+	if cookieValue == "" || h.signer.Verify(cookieValue, &state) != nil || time.Now().Unix() >= state.ExpiresAt {
+		RespondError(c, http.StatusBadRequest, "invalid or expired login state")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(state.State)) != 1 {
+		RespondError(c, http.StatusBadRequest, "invalid or expired login state")
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		RespondError(c, http.StatusBadRequest, "missing authorization code")
+		return
+	}
+
+	identity, err := h.client.Exchange(c.Request.Context(), code, state.Nonce, state.Verifier)
+	if err != nil {
+		RespondUnauthorized(c, "oidc exchange failed: "+err.Error())
+		return
+	}
+
+	value, err := h.signer.EncodeSession(auth.Session{
+		Subject:   "oidc:" + identity.Subject,
+		Name:      identity.Name,
+		Role:      identity.Role,
+		ExpiresAt: time.Now().Add(h.sessionTTL).Unix(),
+	})
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "create session failed")
+		return
+	}
+	h.setCookie(c, auth.SessionCookieName, value, "/", int(h.sessionTTL.Seconds()))
+
+	slog.InfoContext(c.Request.Context(), "OIDC 登录成功", "subject", identity.Subject, "name", identity.Name, "role", identity.Role)
+
+	target := h.redirectURL
+	if state.Redirect != "" {
+		target = state.Redirect
+	}
+	c.Redirect(http.StatusFound, target)
+}
+
+// Logout 清除会话 Cookie 并跳转回前端。
+//
+//	@Summary		登出
+//	@Description	清除会话 Cookie 并跳转回前端
+//	@Tags			auth
+//	@Success		302
+//	@Router			/auth/logout [get]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	h.setCookie(c, auth.SessionCookieName, "", "/", -1)
+	c.Redirect(http.StatusFound, h.redirectURL)
+}
+
+// Me 返回当前请求的认证身份，前端可以据此判断是否已登录。
+//
+//	@Summary		获取当前用户
+//	@Description	返回当前请求的认证身份
+//	@Tags			auth
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"当前身份"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Router			/auth/me [get]
+func (h *AuthHandler) Me(c *gin.Context) {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		// 关闭认证时没有身份信息，视为拥有全部权限
+		respondOK(c, PrincipalResponse{Subject: "anonymous", Name: "anonymous", Method: "none", Role: string(auth.RoleAdmin)})
+		return
+	}
+	respondOK(c, PrincipalResponse{
+		Subject: principal.Subject,
+		Name:    principal.Name,
+		Method:  principal.Method,
+		Role:    string(principal.Role),
+	})
+}
+
+// setCookie 统一设置 HttpOnly、SameSite=Lax 的 Cookie。
+// maxAge 为负数时删除 Cookie。
+func (h *AuthHandler) setCookie(c *gin.Context, name, value, path string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, path, "", h.secureCookie, true)
+}
+
+// randomString 生成 32 字节的随机字符串，用于 state 与 nonce。
+func randomString() string {
+	buf := make([]byte, 32)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// safeRedirect 只接受站内相对路径，防止开放重定向。
+func safeRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return ""
+	}
+	return target
+}
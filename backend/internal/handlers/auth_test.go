@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testClientID      = "todo-test"
+	testSessionSecret = "test-session-secret-test-session-secret"
+)
+
+// mockProvider 是一个最小的 OIDC 身份提供方：发现文档、JWKS 与 token 端点。
+// authorize 模拟用户在登录页完成登录，记录 nonce 与 PKCE challenge 并返回授权码。
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	logins   map[string]mockLogin // 授权码 -> 登录请求
+	verifier string               // token 请求中收到的 code_verifier
+
+	// groups 是写入 ID Token 的 groups claim；nonce 不为空时替换 ID Token 中的 nonce。
+	groups []string
+	nonce  string
+}
+
+type mockLogin struct {
+	nonce     string
+	challenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	p := &mockProvider{t: t, key: key, logins: map[string]mockLogin{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// authorize 校验登录跳转地址中的参数，返回回调时使用的授权码。
+func (p *mockProvider) authorize(location string) (code, state string) {
+	p.t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		p.t.Fatalf("parse authorize url: %v", err)
+	}
+	query := u.Query()
+	if !strings.HasPrefix(location, p.server.URL+"/authorize") {
+		p.t.Fatalf("login redirected to %q, want the provider", location)
+	}
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" || query.Get("nonce") == "" {
+		p.t.Fatalf("authorize request = %v, want client_id, nonce and an S256 code_challenge", query)
+	}
+
+	code = "code-" + query.Get("state")[:8]
+	p.mu.Lock()
+	p.logins[code] = mockLogin{nonce: query.Get("nonce"), challenge: query.Get("code_challenge")}
+	p.mu.Unlock()
+	return code, query.Get("state")
+}
+
+// token 实现授权码换取 Token，要求 code_verifier 与登录时的 code_challenge 匹配。
+func (p *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	login, ok := p.logins[r.PostForm.Get("code")]
+	delete(p.logins, r.PostForm.Get("code"))
+	p.verifier = r.PostForm.Get("code_verifier")
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != login.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	nonce := login.nonce
+	if p.nonce != "" {
+		nonce = p.nonce
+	}
+	claims := map[string]any{
+		"iss":                p.server.URL,
+		"sub":                "user-1",
+		"aud":                testClientID,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"nonce":              nonce,
+		"preferred_username": "alice",
+	}
+	if p.groups != nil {
+		claims["groups"] = p.groups
+	}
+	writeJSON(w, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(claims),
+	})
+}
+
+// sign 生成 RS256 签名的 JWT。
+func (p *mockProvider) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		p.t.Fatalf("sign id_token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// newAuthTestRouter 创建只注册了登录与回调路由的引擎。
+func newAuthTestRouter(p *mockProvider) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	client := auth.NewOIDCClient(auth.OIDCConfig{
+		IssuerURL:      p.server.URL,
+		ClientID:       testClientID,
+		RedirectURL:    "http://todo.test/auth/callback",
+		RoleClaim:      "groups",
+		AdminGroups:    []string{"todo-admins"},
+		OperatorGroups: []string{"todo-operators"},
+		DefaultRole:    auth.RoleViewer,
+	})
+	handler := NewAuthHandler(client, auth.NewSigner(testSessionSecret), time.Hour, false, "/")
+
+	engine := gin.New()
+	engine.GET("/auth/login", handler.Login)
+	engine.GET("/auth/callback", handler.Callback)
+	return engine
+}
+
+// login 请求 /auth/login，返回 state Cookie 与身份提供方登录页地址。
+func login(t *testing.T, engine *gin.Engine, redirect string) (*http.Cookie, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login?redirect="+url.QueryEscape(redirect), nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, body %s", w.Code, w.Body.String())
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == stateCookieName {
+			return cookie, w.Header().Get("Location")
+		}
+	}
+	t.Fatal("login did not set the state cookie")
+	return nil, ""
+}
+
+// callback 携带 state Cookie 请求 /auth/callback。
+func callback(engine *gin.Engine, stateCookie *http.Cookie, code, state string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// sessionFrom 解析响应中的会话 Cookie。
+func sessionFrom(t *testing.T, w *httptest.ResponseRecorder) auth.Session {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == auth.SessionCookieName && cookie.Value != "" {
+			session, err := auth.NewSigner(testSessionSecret).DecodeSession(cookie.Value, time.Now())
+			if err != nil {
+				t.Fatalf("decode session: %v", err)
+			}
+			return session
+		}
+	}
+	t.Fatal("callback did not set the session cookie")
+	return auth.Session{}
+}
+
+func TestOIDCLoginCallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []string
+		want   auth.Role
+	}{
+		{name: "admin group", groups: []string{"staff", "todo-admins"}, want: auth.RoleAdmin},
+		{name: "operator group", groups: []string{"todo-operators"}, want: auth.RoleOperator},
+		{name: "no mapped group falls back to default role", groups: []string{"staff"}, want: auth.RoleViewer},
+		{name: "no groups claim falls back to default role", want: auth.RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newMockProvider(t)
+			provider.groups = tt.groups
+			engine := newAuthTestRouter(provider)
+
+			stateCookie, location := login(t, engine, "/tasks")
+			code, state := provider.authorize(location)
+			w := callback(engine, stateCookie, code, state)
+			if w.Code != http.StatusFound || w.Header().Get("Location") != "/tasks" {
+				t.Fatalf("callback = %d %q, body %s; want 302 to /tasks", w.Code, w.Header().Get("Location"), w.Body.String())
+			}
+			if provider.verifier == "" {
+				t.Fatal("token request carried no PKCE code_verifier")
+			}
+
+			session := sessionFrom(t, w)
+			if session.Subject != "oidc:user-1" || session.Name != "alice" || session.Role != tt.want {
+				t.Fatalf("session = %+v, want oidc:user-1 / alice / %s", session, tt.want)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	provider := newMockProvider(t)
+	engine := newAuthTestRouter(provider)
+
+	stateCookie, location := login(t, engine, "")
+	code, _ := provider.authorize(location)
+
+	// state 与 Cookie 中的不一致，例如另一个浏览器发起的登录（CSRF）
+	if w := callback(engine, stateCookie, code, "forged-state"); w.Code != http.StatusBadRequest {
+		t.Errorf("callback with forged state = %d, want 400", w.Code)
+	}
+
+	// 另一次登录签发的 state Cookie 也不能使用
+	otherCookie, _ := login(t, engine, "")
+	code, state := provider.authorize(location)
+	if w := callback(engine, otherCookie, code, state); w.Code != http.StatusBadRequest {
+		t.Errorf("callback with another login's cookie = %d, want 400", w.Code)
+	}
+
+	// 没有 state Cookie
+	if w := callback(engine, nil, code, state); w.Code != http.StatusBadRequest {
+		t.Errorf("callback without state cookie = %d, want 400", w.Code)
+	}
+
+	// 被篡改的 state Cookie
+	tampered := *stateCookie
+	tampered.Value += "x"
+	if w := callback(engine, &tampered, code, state); w.Code != http.StatusBadRequest {
+		t.Errorf("callback with tampered cookie = %d, want 400", w.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	provider := newMockProvider(t)
+	provider.nonce = "another-nonce"
+	engine := newAuthTestRouter(provider)
+
+	stateCookie, location := login(t, engine, "")
+	code, state := provider.authorize(location)
+	w := callback(engine, stateCookie, code, state)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("callback with wrong nonce = %d, want 401", w.Code)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == auth.SessionCookieName && cookie.Value != "" {
+			t.Fatal("callback with wrong nonce issued a session")
+		}
+	}
+}
+
+func TestOIDCLoginSanitizesRedirect(t *testing.T) {
+	provider := newMockProvider(t)
+	engine := newAuthTestRouter(provider)
+
+	// 不安全的 redirect 被丢弃，登录后跳转到默认地址
+	stateCookie, location := login(t, engine, "https://evil.example.com/")
+	code, state := provider.authorize(location)
+	w := callback(engine, stateCookie, code, state)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Fatalf("callback = %d %q, want 302 to /", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"/tasks":                   "/tasks",
+		"/tasks?filter=open#top":   "/tasks?filter=open#top",
+		"":                         "",
+		"tasks":                    "",
+		"https://evil.example.com": "",
+		"//evil.example.com/path":  "",
+		`/\evil.example.com`:       "",
+		"javascript:alert(1)":      "",
+	}
+	for input, want := range tests {
+		if got := safeRedirect(input); got != want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package handlers 包含通知发件箱相关的处理逻辑。
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"backend/internal/repo"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Waker 在死信消息重新入队后唤醒发件箱 Worker。
+// notify.Outbox 实现了该接口；未启用推送时可以传 nil。
+type Waker interface {
+	Wake()
+}
+
+// NotificationHandler 处理通知发件箱的查询与重新入队请求。
+type NotificationHandler struct {
+	repo  *repo.OutboxRepository
+	waker Waker
+}
+
+// NewNotificationHandler 创建 NotificationHandler 实例。
+func NewNotificationHandler(repo *repo.OutboxRepository, waker Waker) *NotificationHandler {
+	return &NotificationHandler{repo: repo, waker: waker}
+}
+
+// ListFailed 获取发送失败（死信状态）的通知列表。
+//
+//	@Summary		获取发送失败的通知
+//	@Description	返回重试次数耗尽、进入死信状态的通知，最近失败的在前面
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"成功返回通知列表"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/notifications/failed [get]
+func (h *NotificationHandler) ListFailed(c *gin.Context) {
+	items, err := h.repo.ListDead()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "list failed notifications failed")
+		return
+	}
+
+	response := make([]NotificationResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, toNotificationResponse(item))
+	}
+	respondOK(c, response)
+}
+
+// Requeue 将死信通知重新放回发送队列。
+//
+//	@Summary		重新发送失败的通知
+//	@Description	将死信状态的通知重新入队，尝试次数清零并立即发送
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int						true	"通知 ID"
+//	@Success		200	{object}	map[string]interface{}	"成功返回重新入队后的通知"
+//	@Failure		400	{object}	map[string]string		"请求参数错误"
+//	@Failure		404	{object}	map[string]string		"通知不存在"
+//	@Failure		409	{object}	map[string]string		"通知不处于死信状态"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/notifications/{id}/requeue [post]
+func (h *NotificationHandler) Requeue(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.Requeue(id, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "notification not found")
+			return
+		}
+		if errors.Is(err, repo.ErrNotDead) {
+			RespondError(c, http.StatusConflict, "notification is not dead-lettered")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "requeue notification failed")
+		return
+	}
+
+	if h.waker != nil {
+		h.waker.Wake()
+	}
+	respondOK(c, toNotificationResponse(item))
+}
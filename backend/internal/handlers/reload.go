@@ -0,0 +1,57 @@
+// Package handlers 包含重新加载配置相关的处理逻辑。
+package handlers
+
+import (
+	"net/http"
+
+	"backend/internal/reload"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReloadHandler 处理重新加载配置与查询重新加载状态的请求。
+type ReloadHandler struct {
+	manager *reload.Manager
+}
+
+// NewReloadHandler 创建 ReloadHandler 实例。
+func NewReloadHandler(manager *reload.Manager) *ReloadHandler {
+	return &ReloadHandler{manager: manager}
+}
+
+// Reload 重新加载配置。
+//
+//	@Summary		重新加载配置
+//	@Description	重新读取配置文件、环境变量与命令行参数，CORS 来源、Webhook Secret、推送目标与日志级别立即生效
+//	@Description	其余配置项的变化在 restartRequired 中列出，需要重启服务才能生效
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"重新加载成功，返回重新加载状态"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		422	{object}	map[string]string		"新配置无效，继续使用原配置"
+//	@Router			/admin/reload [post]
+func (h *ReloadHandler) Reload(c *gin.Context) {
+	status := h.manager.Reload(reload.TriggerAPI)
+	if !status.Success {
+		RespondError(c, http.StatusUnprocessableEntity, "reload failed: "+status.Error)
+		return
+	}
+	respondOK(c, status)
+}
+
+// Status 返回最近一次重新加载的结果。
+//
+//	@Summary		获取重新加载状态
+//	@Description	返回最近一次重新加载配置的触发方式、时间、结果以及需要重启才能生效的配置项
+//	@Tags			admin
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"最近一次重新加载的状态"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Router			/admin/reload [get]
+func (h *ReloadHandler) Status(c *gin.Context) {
+	respondOK(c, h.manager.Status())
+}
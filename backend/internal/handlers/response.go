@@ -3,10 +3,11 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"backend/internal/logging"
 	"backend/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -16,11 +17,21 @@ import (
 // 使用 `json:"..."` 标签控制序列化时的字段名。
 // 前后端分离开发中，通常返回驼峰命名 (camelCase) 的 JSON 字段。
 type TodoResponse struct {
-	ID          uint       `json:"id"`
-	SecretPath  string     `json:"secretPath"`
-	IsCompleted bool       `json:"isCompleted"`
-	CreatedAt   string     `json:"createdAt"`   // 格式化后的时间字符串
-	CompletedAt *string    `json:"completedAt"` // 指针类型，允许为 null
+	ID           uint    `json:"id"`
+	ProjectID    string  `json:"projectId"`
+	ProjectName  string  `json:"projectName"`
+	Environment  string  `json:"environment"`
+	SecretPath   string  `json:"secretPath"`
+	SecretName   string  `json:"secretName"`
+	ReminderNote string  `json:"reminderNote"`
+	IsCompleted  bool    `json:"isCompleted"`
+	CreatedAt    string  `json:"createdAt"`   // 格式化后的时间字符串
+	CompletedAt  *string `json:"completedAt"` // 指针类型，允许为 null
+
+	CompletedBy    string `json:"completedBy"`    // 标记完成的操作者，未完成时为空
+	CompletionNote string `json:"completionNote"` // 完成备注，未完成时为空
+
+	Source string `json:"source"` // Webhook 来源标识，手动创建或来自默认 Webhook 地址时为空
 }
 
 const timeLayout = time.RFC3339
@@ -29,10 +40,20 @@ const timeLayout = time.RFC3339
 // 这种 DTO (Data Transfer Object) 模式可以隔离数据库结构和 API 契约。
 func toTodoResponse(item models.TodoItem) TodoResponse {
 	response := TodoResponse{
-		ID:          item.ID,
-		SecretPath:  item.SecretPath,
-		IsCompleted: item.IsCompleted,
-		CreatedAt:   item.CreatedAt.Format(timeLayout),
+		ID:           item.ID,
+		ProjectID:    item.ProjectID,
+		ProjectName:  item.ProjectName,
+		Environment:  item.Environment,
+		SecretPath:   item.SecretPath,
+		SecretName:   item.SecretName,
+		ReminderNote: item.ReminderNote,
+		IsCompleted:  item.IsCompleted,
+		CreatedAt:    item.CreatedAt.Format(timeLayout),
+
+		CompletedBy:    item.CompletedBy,
+		CompletionNote: item.CompletionNote,
+
+		Source: item.Source,
 	}
 	if item.CompletedAt != nil {
 		formatted := item.CompletedAt.Format(timeLayout)
@@ -41,6 +62,140 @@ func toTodoResponse(item models.TodoItem) TodoResponse {
 	return response
 }
 
+// TodoEventResponse 定义了事件时间线中单条记录的 JSON 结构。
+type TodoEventResponse struct {
+	ID                 uint   `json:"id"`
+	TodoID             uint   `json:"todoId"`
+	EventType          string `json:"eventType"`
+	ReceivedAt         string `json:"receivedAt"`         // 后端接收时间
+	InfisicalTimestamp int64  `json:"infisicalTimestamp"` // 载荷中 Infisical 自带的时间戳，原样返回
+	SourceIP           string `json:"sourceIp"`
+	BodyHash           string `json:"bodyHash"` // 原始请求体的 SHA-256 摘要
+}
+
+// toTodoEventResponse 将事件模型转换为 API 响应模型。
+func toTodoEventResponse(event models.TodoEvent) TodoEventResponse {
+	return TodoEventResponse{
+		ID:                 event.ID,
+		TodoID:             event.TodoID,
+		EventType:          event.EventType,
+		ReceivedAt:         event.ReceivedAt.Format(timeLayout),
+		InfisicalTimestamp: event.InfisicalTimestamp,
+		SourceIP:           event.SourceIP,
+		BodyHash:           event.BodyHash,
+	}
+}
+
+// NotificationResponse 定义了通知发件箱中单条消息的 JSON 结构。
+type NotificationResponse struct {
+	ID            uint    `json:"id"`
+	TodoID        uint    `json:"todoId"`
+	Title         string  `json:"title"`
+	Body          string  `json:"body"`
+	Status        string  `json:"status"` // pending / delivered / dead
+	Attempts      int     `json:"attempts"`
+	NextAttemptAt string  `json:"nextAttemptAt"`
+	LastError     string  `json:"lastError"`
+	CreatedAt     string  `json:"createdAt"`
+	DeliveredAt   *string `json:"deliveredAt"` // 未发送成功时为 null
+}
+
+// toNotificationResponse 将发件箱模型转换为 API 响应模型。
+func toNotificationResponse(item models.NotificationOutbox) NotificationResponse {
+	response := NotificationResponse{
+		ID:            item.ID,
+		TodoID:        item.TodoID,
+		Title:         item.Title,
+		Body:          item.Body,
+		Status:        item.Status,
+		Attempts:      item.Attempts,
+		NextAttemptAt: item.NextAttemptAt.Format(timeLayout),
+		LastError:     item.LastError,
+		CreatedAt:     item.CreatedAt.Format(timeLayout),
+	}
+	if item.DeliveredAt != nil {
+		formatted := item.DeliveredAt.Format(timeLayout)
+		response.DeliveredAt = &formatted
+	}
+	return response
+}
+
+// TokenResponse 定义了 API Token 元数据的 JSON 结构，不包含明文。
+type TokenResponse struct {
+	ID         uint    `json:"id"`
+	Name       string  `json:"name"`
+	Role       string  `json:"role"`
+	Prefix     string  `json:"prefix"` // Token 明文的前几位，便于辨认
+	CreatedAt  string  `json:"createdAt"`
+	LastUsedAt *string `json:"lastUsedAt"` // 从未使用时为 null
+}
+
+// CreatedTokenResponse 是创建 Token 接口的响应，额外包含只返回一次的明文。
+type CreatedTokenResponse struct {
+	TokenResponse
+	Token string `json:"token"`
+}
+
+// toTokenResponse 将 Token 模型转换为 API 响应模型。
+func toTokenResponse(item models.APIToken) TokenResponse {
+	response := TokenResponse{
+		ID:        item.ID,
+		Name:      item.Name,
+		Role:      item.Role,
+		Prefix:    item.Prefix,
+		CreatedAt: item.CreatedAt.Format(timeLayout),
+	}
+	if item.LastUsedAt != nil {
+		formatted := item.LastUsedAt.Format(timeLayout)
+		response.LastUsedAt = &formatted
+	}
+	return response
+}
+
+// WebhookSourceResponse 定义了 Webhook 来源的 JSON 结构，不包含 Secret。
+type WebhookSourceResponse struct {
+	ID           uint     `json:"id"`
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	URL          string   `json:"url"` // 在 Infisical 中填写的 Webhook 地址（不含域名）
+	ProjectLabel string   `json:"projectLabel"`
+	Events       []string `json:"events"` // 为空表示接受所有支持的事件
+	CreatedAt    string   `json:"createdAt"`
+	UpdatedAt    string   `json:"updatedAt"`
+}
+
+// CreatedWebhookSourceResponse 是创建来源接口的响应，额外包含只返回一次的 Secret。
+type CreatedWebhookSourceResponse struct {
+	WebhookSourceResponse
+	Secret string `json:"secret"`
+}
+
+// toWebhookSourceResponse 将 Webhook 来源模型转换为 API 响应模型。
+func toWebhookSourceResponse(item models.WebhookSource) WebhookSourceResponse {
+	events := item.EventList()
+	if events == nil {
+		events = []string{}
+	}
+	return WebhookSourceResponse{
+		ID:           item.ID,
+		Slug:         item.Slug,
+		Name:         item.Name,
+		URL:          webhookSourcePath(item),
+		ProjectLabel: item.ProjectLabel,
+		Events:       events,
+		CreatedAt:    item.CreatedAt.Format(timeLayout),
+		UpdatedAt:    item.UpdatedAt.Format(timeLayout),
+	}
+}
+
+// PrincipalResponse 定义了当前认证身份的 JSON 结构。
+type PrincipalResponse struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name"`
+	Method  string `json:"method"` // token / session / none
+	Role    string `json:"role"`
+}
+
 // respondData 统一封装成功响应（带数据）。
 // 格式：{"data": ...}
 func respondData(c *gin.Context, status int, data interface{}) {
@@ -52,20 +207,41 @@ func respondOK(c *gin.Context, data interface{}) {
 	respondData(c, http.StatusOK, data)
 }
 
+// respondPage 在 respondOK 的 {"data": ...} 结构上附加分页信息。
+// 格式：{"data": [...], "total": 123, "nextCursor": "..." | null}
+// 旧客户端只读取 data 字段，不受影响。
+func respondPage(c *gin.Context, data interface{}, total int64, nextCursor string) {
+	var cursor *string
+	if nextCursor != "" {
+		cursor = &nextCursor
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data, "total": total, "nextCursor": cursor})
+}
+
 // RespondError 统一封装错误响应。
-// 格式：{"error": "message"}
-// 这让前端可以统一处理错误逻辑。
+// 格式：{"error": "message", "requestId": "..."}
+// 这让前端可以统一处理错误逻辑；requestId 与日志中的 request_id 一致，便于排查问题。
 // 导出供 handlers 和 middleware 包使用。
 func RespondError(c *gin.Context, status int, message string) {
-	c.JSON(status, gin.H{"error": message})
+	c.JSON(status, errorBody(c, message))
 }
 
-// respondUnauthorized 统一返回 unauthorized 错误，但在后端日志中记录具体原因。
+// RespondUnauthorized 统一返回 unauthorized 错误，但在后端日志中记录具体原因。
 // 这样可以避免向客户端泄露敏感的错误信息，同时方便后端调试。
+// 导出供 Webhook 签名校验和认证中间件共同使用。
 // actualReason: 实际的错误原因，会记录到日志中
-func respondUnauthorized(c *gin.Context, actualReason string) {
+func RespondUnauthorized(c *gin.Context, actualReason string) {
 	// 记录具体的错误原因到后端日志
-	log.Printf("[Unauthorized] Path: %s, Reason: %s", c.Request.URL.Path, actualReason)
+	slog.WarnContext(c.Request.Context(), "请求未通过认证", "path", c.Request.URL.Path, "reason", actualReason)
 	// 统一返回 unauthorized 给客户端
-	c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-}
\ No newline at end of file
+	c.JSON(http.StatusUnauthorized, errorBody(c, "unauthorized"))
+}
+
+// errorBody 构造错误响应体，请求带有请求 ID 时一并返回。
+func errorBody(c *gin.Context, message string) gin.H {
+	body := gin.H{"error": message}
+	if requestID := logging.RequestID(c.Request.Context()); requestID != "" {
+		body["requestId"] = requestID
+	}
+	return body
+}
@@ -0,0 +1,152 @@
+// Package handlers 包含待办事项实时推送（Server-Sent Events）的处理逻辑。
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// streamHeartbeatInterval 是心跳间隔。
+	// 定期发送注释行可以防止反向代理因连接空闲而断开，也能及时发现已断开的客户端。
+	streamHeartbeatInterval = 15 * time.Second
+
+	// streamRetry 是建议客户端断线后重连的等待时间（毫秒）。
+	streamRetry = 3000
+
+	// eventResync 提示客户端无法补发错过的事件，需要重新拉取完整列表。
+	eventResync = "resync"
+)
+
+// StreamHandler 通过 Server-Sent Events 推送待办事项变更。
+type StreamHandler struct {
+	broadcaster *events.Broadcaster
+
+	// heartbeatInterval 是心跳间隔，默认为 streamHeartbeatInterval，测试中可以缩短。
+	heartbeatInterval time.Duration
+}
+
+// NewStreamHandler 创建 StreamHandler 实例。
+func NewStreamHandler(broadcaster *events.Broadcaster) *StreamHandler {
+	return &StreamHandler{broadcaster: broadcaster, heartbeatInterval: streamHeartbeatInterval}
+}
+
+// streamEvent 是 SSE 事件 data 字段的 JSON 结构。
+type streamEvent struct {
+	Type events.Type  `json:"type"`
+	Todo TodoResponse `json:"todo"`
+	At   string       `json:"at"`
+}
+
+// Stream 建立 SSE 连接并持续推送待办事项变更。
+//
+//	@Summary		订阅待办事项变更
+//	@Description	以 Server-Sent Events 推送待办事项变更，事件名为 todo.created、todo.reset、todo.completed、todo.reopened、todo.deleted
+//	@Description	断线重连时浏览器会自动携带 Last-Event-ID 头，服务端补发错过的事件；
+//	@Description	无法补发时（断线过久或服务重启）发送 resync 事件，客户端应重新拉取列表
+//	@Tags			todos
+//	@Produce		text/event-stream
+//	@Security		BearerAuth
+//	@Param			Last-Event-ID	header		string	false	"最后收到的事件 ID"
+//	@Success		200				{string}	string	"事件流"
+//	@Failure		401				{object}	map[string]string	"未认证"
+//	@Failure		403				{object}	map[string]string	"权限不足"
+//	@Router			/todos/stream [get]
+func (h *StreamHandler) Stream(c *gin.Context) {
+	// 浏览器重连时通过请求头携带 Last-Event-ID；
+	// 也允许通过查询参数传递，方便首次连接时从已知位置开始。
+	rawLastID := c.GetHeader("Last-Event-ID")
+	if rawLastID == "" {
+		rawLastID = c.Query("lastEventId")
+	}
+	var lastID uint64
+	if rawLastID != "" {
+		parsed, err := strconv.ParseUint(rawLastID, 10, 64)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID = parsed
+	}
+
+	// SSE 是长连接，解除 http.Server 的读写超时，否则连接会在超时到达时被断开。
+	// 连接是否存活由心跳检测。
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	sub, backlog, complete := h.broadcaster.Subscribe(lastID)
+	defer sub.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 关闭 nginx 的响应缓冲，否则事件会被攒到缓冲区满才发送
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry)
+	if !complete {
+		// 携带最新 ID，客户端重新拉取列表后从这里继续
+		fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: {}\n\n", h.broadcaster.LastID(), eventResync)
+	}
+	for _, event := range backlog {
+		if err := writeStreamEvent(c, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			// 客户端断开连接
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// 服务正在关闭，客户端会按 retry 的间隔携带 Last-Event-ID 重连
+				if h.broadcaster.Closed() {
+					return
+				}
+				// 消费过慢被广播器断开，客户端会携带 Last-Event-ID 重连补发
+				slog.WarnContext(c.Request.Context(), "SSE 订阅者因消费过慢被断开", "client_ip", c.ClientIP())
+				return
+			}
+			if err := writeStreamEvent(c, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeStreamEvent 按 SSE 格式写入一个事件。
+func writeStreamEvent(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(streamEvent{
+		Type: event.Type,
+		Todo: toTodoResponse(event.Todo),
+		At:   event.At.Format(timeLayout),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseMessage 是事件流中以空行分隔的一段。
+type sseMessage struct {
+	id, event, data, retry string
+	comment                string
+}
+
+// sseClient 读取一条 SSE 连接。
+type sseClient struct {
+	t      *testing.T
+	reader *bufio.Reader
+}
+
+// openStream 建立 SSE 连接，lastEventID 不为空时携带 Last-Event-ID 头。
+func openStream(t *testing.T, server *httptest.Server, lastEventID string) *sseClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/stream", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /stream = %d %q, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return &sseClient{t: t, reader: bufio.NewReader(resp.Body)}
+}
+
+// next 读取下一段消息。
+func (c *sseClient) next() sseMessage {
+	c.t.Helper()
+	var msg sseMessage
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			msg.comment = value
+		case "id":
+			msg.id = value
+		case "event":
+			msg.event = value
+		case "data":
+			msg.data = value
+		case "retry":
+			msg.retry = value
+		}
+	}
+}
+
+func newStreamTestServer(t *testing.T, handler *StreamHandler) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/stream", handler.Stream)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	handler := NewStreamHandler(events.NewBroadcaster(0))
+	handler.heartbeatInterval = 10 * time.Millisecond
+	client := openStream(t, newStreamTestServer(t, handler), "")
+
+	if msg := client.next(); msg.retry != strconv.Itoa(streamRetry) {
+		t.Fatalf("first message = %+v, want retry", msg)
+	}
+	for i := 0; i < 2; i++ {
+		if msg := client.next(); msg.comment != "ping" {
+			t.Fatalf("message = %+v, want a ping comment", msg)
+		}
+	}
+}
+
+func TestStreamResume(t *testing.T) {
+	broadcaster := events.NewBroadcaster(3)
+	server := newStreamTestServer(t, NewStreamHandler(broadcaster))
+
+	// 历史只保留最近 3 个事件，第一个事件已经被挤出
+	var published []events.Event
+	for i := 0; i < 4; i++ {
+		published = append(published, broadcaster.Publish(events.TodoCreated, models.TodoItem{ID: uint(i + 1)}))
+	}
+	id := func(event events.Event) string { return strconv.FormatUint(event.ID, 10) }
+
+	// 从第一个事件之后恢复：补发仍在历史中的其余事件，之后继续推送新事件
+	client := openStream(t, server, id(published[0]))
+	client.next() // retry
+	for _, want := range published[1:] {
+		if msg := client.next(); msg.id != id(want) || msg.event != string(events.TodoCreated) {
+			t.Fatalf("replayed message = %+v, want event %s", msg, id(want))
+		}
+	}
+	live := broadcaster.Publish(events.TodoDeleted, models.TodoItem{ID: 1})
+	if msg := client.next(); msg.id != id(live) || msg.event != string(events.TodoDeleted) {
+		t.Fatalf("live message = %+v, want event %s", msg, id(live))
+	}
+
+	// 要求补发的事件已不在历史中：发送携带最新 ID 的 resync，而不是补发一部分
+	client = openStream(t, server, strconv.FormatUint(published[0].ID-1, 10))
+	client.next() // retry
+	if msg := client.next(); msg.event != eventResync || msg.id != id(live) {
+		t.Fatalf("message = %+v, want resync with id %s", msg, id(live))
+	}
+
+	resp, err := http.Get(server.URL + "/stream?lastEventId=abc")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid lastEventId = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestTodoHandlerPublishesEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broadcaster := events.NewBroadcaster(0)
+	handler := NewTodoHandler(repo.NewTodoRepository(testdb.SQLite(t)), broadcaster)
+	engine := gin.New()
+	engine.POST("/todos", handler.Create)
+	engine.PATCH("/todos/:id", handler.Update)
+	engine.POST("/todos/:id/complete", handler.Complete)
+	engine.POST("/todos/:id/reopen", handler.Reopen)
+	engine.DELETE("/todos/:id", handler.Delete)
+
+	sub, _, _ := broadcaster.Subscribe(0)
+	defer sub.Close()
+
+	steps := []struct {
+		method, path, body string
+		want               events.Type
+	}{
+		{method: http.MethodPost, path: "/todos", body: `{"secretPath": "/db"}`, want: events.TodoCreated},
+		{method: http.MethodPatch, path: "/todos/1", body: `{"isCompleted": true}`, want: events.TodoCompleted},
+		{method: http.MethodPatch, path: "/todos/1", want: events.TodoReopened},
+		{method: http.MethodPost, path: "/todos/1/complete", want: events.TodoCompleted},
+		{method: http.MethodPost, path: "/todos/1/reopen", want: events.TodoReopened},
+		{method: http.MethodDelete, path: "/todos/1", want: events.TodoDeleted},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s = %d %s, want 200", step.method, step.path, w.Code, w.Body)
+		}
+		select {
+		case event := <-sub.Events():
+			if event.Type != step.want || event.Todo.ID != 1 {
+				t.Fatalf("%s %s published %s for todo %d, want %s for todo 1", step.method, step.path, event.Type, event.Todo.ID, step.want)
+			}
+		default:
+			t.Fatalf("%s %s published no event, want %s", step.method, step.path, step.want)
+		}
+	}
+
+	// 请求失败时不发布事件
+	req := httptest.NewRequest(http.MethodDelete, "/todos/1", nil)
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("failed DELETE published %s", event.Type)
+	default:
+	}
+}
+
+func TestStreamEndsOnShutdown(t *testing.T) {
+	broadcaster := events.NewBroadcaster(0)
+	client := openStream(t, newStreamTestServer(t, NewStreamHandler(broadcaster)), "")
+	client.next() // retry
+
+	// 关闭广播器后连接立即结束，而不是等到 http.Server 的排空期限
+	broadcaster.Close()
+	_, err := client.reader.ReadString('\n')
+	if err == nil {
+		t.Fatal("stream still open after broadcaster.Close")
+	}
+}
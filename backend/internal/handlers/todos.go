@@ -4,11 +4,16 @@ package handlers
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/models"
 	"backend/internal/repo"
 
 	"github.com/gin-gonic/gin"
@@ -17,44 +22,109 @@ import (
 
 // TodoHandler 结构体持有 Repository 的引用。
 // 这样可以在处理请求时调用数据库操作。
+// 修改待办事项后会通过 broadcaster 广播变更事件，供实时推送接口使用。
 type TodoHandler struct {
-	repo *repo.TodoRepository
+	repo        *repo.TodoRepository
+	broadcaster *events.Broadcaster
 }
 
 // NewTodoHandler 创建一个新的 TodoHandler。
-func NewTodoHandler(repo *repo.TodoRepository) *TodoHandler {
-	return &TodoHandler{repo: repo}
+func NewTodoHandler(repo *repo.TodoRepository, broadcaster *events.Broadcaster) *TodoHandler {
+	return &TodoHandler{repo: repo, broadcaster: broadcaster}
 }
 
 // todoInput 定义了创建接口的请求体结构。
 type todoInput struct {
 	// 反射标签指定了 JSON 字段名。
 	SecretPath string `json:"secretPath"`
+
+	// 以下字段可选，用于区分不同 Infisical 项目或环境下的同名路径。
+	ProjectID    string `json:"projectId"`
+	ProjectName  string `json:"projectName"`
+	Environment  string `json:"environment"`
+	SecretName   string `json:"secretName"`
+	ReminderNote string `json:"reminderNote"`
 }
 
-// List 获取所有待办事项列表。
+// maxCompletionNoteLength 是完成备注的最大字符数。
+const maxCompletionNoteLength = 1000
+
+// completeInput 定义了标记完成接口的可选请求体结构。
+type completeInput struct {
+	// Note 是完成备注，例如已经同步更新了哪些服务。仅在标记为完成时保存。
+	Note string `json:"note"`
+}
+
+// updateInput 定义了 PATCH 接口的可选请求体结构。
+type updateInput struct {
+	// IsCompleted 是目标状态。携带请求体时必填；只有完全没有请求体时才切换当前状态，兼容旧客户端。
+	IsCompleted *bool `json:"isCompleted"`
+
+	// Note 是完成备注，仅在标记为完成时保存。
+	Note string `json:"note"`
+}
+
+// maxListLimit 是单页最多返回的记录数。
+const maxListLimit = 200
+
+// todoSortFields 将查询参数中的排序字段映射为 Repository 的排序列。
+var todoSortFields = map[string]repo.TodoSort{
+	"id":         repo.SortByID,
+	"createdAt":  repo.SortByCreatedAt,
+	"secretPath": repo.SortBySecretPath,
+}
+
+// List 获取待办事项列表，支持过滤、搜索、排序与游标分页。
 //
 //	@Summary		获取待办事项列表
-//	@Description	获取所有待办事项的列表
+//	@Description	按条件查询待办事项。不传 limit 时返回全部匹配记录，与旧版行为一致
+//	@Description	时间范围为左闭右开区间，接受 RFC3339 时间或 YYYY-MM-DD 日期（UTC）
+//	@Description	分页时将响应中的 nextCursor 作为下一次请求的 cursor，排序参数需保持不变
 //	@Tags			todos
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	map[string]interface{}	"成功返回待办事项列表"
-//	@Failure		500	{object}	map[string]string		"服务器内部错误"
-//	@Router			/ [get]
+//	@Security		BearerAuth
+//	@Param			status			query		string					false	"完成状态"	Enums(all, open, completed)
+//	@Param			pathPrefix		query		string					false	"密钥路径前缀"
+//	@Param			q				query		string					false	"关键字，匹配路径、密钥名、项目名、环境与提醒备注"
+//	@Param			projectId		query		string					false	"Infisical 项目 ID"
+//	@Param			environment		query		string					false	"Infisical 环境标识"
+//	@Param			source			query		string					false	"Webhook 来源标识，传空值时只返回没有来源的待办事项"
+//	@Param			createdFrom		query		string					false	"创建时间起点（含）"
+//	@Param			createdTo		query		string					false	"创建时间终点（不含）"
+//	@Param			completedFrom	query		string					false	"完成时间起点（含）"
+//	@Param			completedTo		query		string					false	"完成时间终点（不含）"
+//	@Param			sort			query		string					false	"排序字段，默认 id"	Enums(id, createdAt, secretPath)
+//	@Param			order			query		string					false	"排序方向，默认 desc"	Enums(asc, desc)
+//	@Param			limit			query		int						false	"每页条数，最大 200"
+//	@Param			cursor			query		string					false	"分页游标"
+//	@Success		200				{object}	map[string]interface{}	"成功返回待办事项列表、总数 total 与下一页游标 nextCursor"
+//	@Failure		400				{object}	map[string]string		"查询参数错误"
+//	@Failure		401				{object}	map[string]string		"未认证"
+//	@Failure		500				{object}	map[string]string		"服务器内部错误"
+//	@Router			/todos [get]
 func (h *TodoHandler) List(c *gin.Context) {
-	items, err := h.repo.List()
+	filter, ok := parseTodoFilter(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.repo.WithContext(c.Request.Context()).List(filter)
 	if err != nil {
+		if errors.Is(err, repo.ErrInvalidCursor) {
+			RespondError(c, http.StatusBadRequest, "invalid cursor")
+			return
+		}
 		RespondError(c, http.StatusInternalServerError, "list todos failed")
 		return
 	}
 
 	// 将数据库模型切片转换为响应模型切片
-	response := make([]TodoResponse, 0, len(items))
-	for _, item := range items {
+	response := make([]TodoResponse, 0, len(page.Items))
+	for _, item := range page.Items {
 		response = append(response, toTodoResponse(item))
 	}
-	respondOK(c, response)
+	respondPage(c, response, page.Total, page.NextCursor)
 }
 
 // Create 创建新的待办事项。
@@ -64,12 +134,15 @@ func (h *TodoHandler) List(c *gin.Context) {
 //	@Tags			todos
 //	@Accept			json
 //	@Produce		json
+//	@Security		BearerAuth
 //	@Param			todo	body		todoInput				true	"待办事项信息"
 //	@Success		200		{object}	map[string]interface{}	"成功返回创建的待办事项"
 //	@Failure		400		{object}	map[string]string		"请求参数错误"
-//	@Failure		409		{object}	map[string]string		"密钥路径已存在"
+//	@Failure		409		{object}	map[string]string		"同一项目、环境下的密钥路径已存在"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		403		{object}	map[string]string		"权限不足"
 //	@Failure		500		{object}	map[string]string		"服务器内部错误"
-//	@Router			/ [post]
+//	@Router			/todos [post]
 func (h *TodoHandler) Create(c *gin.Context) {
 	var input todoInput
 	// ShouldBindJSON 解析请求体中的 JSON 并绑定到 input 结构体。
@@ -85,16 +158,24 @@ func (h *TodoHandler) Create(c *gin.Context) {
 		return
 	}
 
-	item, err := h.repo.Create(secretPath, time.Now().UTC())
+	item, err := h.repo.WithContext(c.Request.Context()).Create(repo.TodoFields{
+		ProjectID:    strings.TrimSpace(input.ProjectID),
+		ProjectName:  strings.TrimSpace(input.ProjectName),
+		Environment:  strings.TrimSpace(input.Environment),
+		SecretPath:   secretPath,
+		SecretName:   strings.TrimSpace(input.SecretName),
+		ReminderNote: strings.TrimSpace(input.ReminderNote),
+	}, time.Now().UTC())
 	if err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
-			RespondError(c, http.StatusConflict, "secretPath already exists")
+			RespondError(c, http.StatusConflict, "todo for this project, environment and secretPath already exists")
 			return
 		}
 		RespondError(c, http.StatusInternalServerError, "create todo failed")
 		return
 	}
 
+	h.broadcaster.Publish(events.TodoCreated, item)
 	respondOK(c, toTodoResponse(item))
 }
 
@@ -105,12 +186,14 @@ func (h *TodoHandler) Create(c *gin.Context) {
 //	@Tags			todos
 //	@Accept			json
 //	@Produce		json
+//	@Security		BearerAuth
 //	@Param			id		path		int						true	"待办事项 ID"
 //	@Success		200		{object}	map[string]interface{}	"成功返回待办事项"
 //	@Failure		400		{object}	map[string]string		"请求参数错误"
 //	@Failure		404		{object}	map[string]string		"待办事项不存在"
+//	@Failure		401		{object}	map[string]string		"未认证"
 //	@Failure		500		{object}	map[string]string		"服务器内部错误"
-//	@Router			/{id} [get]
+//	@Router			/todos/{id} [get]
 func (h *TodoHandler) Get(c *gin.Context) {
 	// 未找到 ID 则返回 400 错误
 	id, ok := parseID(c)
@@ -118,7 +201,7 @@ func (h *TodoHandler) Get(c *gin.Context) {
 		return
 	}
 
-	item, err := h.repo.GetByID(id)
+	item, err := h.repo.WithContext(c.Request.Context()).GetByID(id)
 	if err != nil {
 		// 处理未找到的情况
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -133,20 +216,27 @@ func (h *TodoHandler) Get(c *gin.Context) {
 	respondOK(c, toTodoResponse(item))
 }
 
-// ToggleComplete 切换待办事项的完成状态。
+// Update 更新待办事项的完成状态。
 //
-//	@Summary		切换待办事项完成状态
-//	@Description	切换指定 ID 的待办事项的完成状态（已完成↔未完成）
+//	@Summary		更新待办事项完成状态
+//	@Description	请求体携带 isCompleted 时将待办事项设置为目标状态（幂等）
+//	@Description	无请求体时切换当前状态（已完成↔未完成），兼容旧客户端
+//	@Description	携带请求体但未指定 isCompleted 时返回 400
+//	@Description	标记为完成时会记录操作者，并可附带完成备注
 //	@Tags			todos
 //	@Accept			json
 //	@Produce		json
+//	@Security		BearerAuth
 //	@Param			id		path		int						true	"待办事项 ID"
-//	@Success		200		{object}	map[string]interface{}	"成功返回切换后的待办事项"
+//	@Param			body	body		updateInput				false	"可选的目标状态与完成备注"
+//	@Success		200		{object}	map[string]interface{}	"成功返回更新后的待办事项"
 //	@Failure		400		{object}	map[string]string		"请求参数错误"
 //	@Failure		404		{object}	map[string]string		"待办事项不存在"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		403		{object}	map[string]string		"权限不足"
 //	@Failure		500		{object}	map[string]string		"服务器内部错误"
-//	@Router			/{id} [patch]
-func (h *TodoHandler) ToggleComplete(c *gin.Context) {
+//	@Router			/todos/{id} [patch]
+func (h *TodoHandler) Update(c *gin.Context) {
 	// 从 URL 参数获取 ID
 	// 未找到 ID 则返回 400 错误
 	id, ok := parseID(c)
@@ -154,19 +244,143 @@ func (h *TodoHandler) ToggleComplete(c *gin.Context) {
 		return
 	}
 
-	item, err := h.repo.ToggleComplete(id, time.Now().UTC())
+	// 请求体是可选的，空请求体视为切换状态；
+	// 携带请求体时必须指定 isCompleted，避免 {"note": "..."} 之类的请求被误当作切换
+	var input updateInput
+	present, ok := bindOptionalJSON(c, &input)
+	if !ok {
+		return
+	}
+	if present && input.IsCompleted == nil {
+		RespondError(c, http.StatusBadRequest, "isCompleted is required")
+		return
+	}
+	note, ok := parseNote(c, input.Note)
+	if !ok {
+		return
+	}
+
+	var (
+		item models.TodoItem
+		err  error
+	)
+	switch {
+	case !present:
+		item, err = h.repo.WithContext(c.Request.Context()).ToggleComplete(id, time.Now().UTC(), actorName(c), note)
+	case *input.IsCompleted:
+		item, err = h.repo.WithContext(c.Request.Context()).Complete(id, time.Now().UTC(), actorName(c), note)
+	default:
+		item, err = h.repo.WithContext(c.Request.Context()).Reopen(id, time.Now().UTC())
+	}
+	h.respondUpdate(c, item, err)
+}
+
+// Complete 将待办事项标记为已完成。
+//
+//	@Summary		标记待办事项为已完成
+//	@Description	将指定 ID 的待办事项标记为已完成，并记录操作者与可选的完成备注
+//	@Description	对已完成的待办事项重复调用不会改变其状态与完成信息
+//	@Tags			todos
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		int						true	"待办事项 ID"
+//	@Param			body	body		completeInput			false	"可选的完成备注"
+//	@Success		200		{object}	map[string]interface{}	"成功返回更新后的待办事项"
+//	@Failure		400		{object}	map[string]string		"请求参数错误"
+//	@Failure		404		{object}	map[string]string		"待办事项不存在"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		403		{object}	map[string]string		"权限不足"
+//	@Failure		500		{object}	map[string]string		"服务器内部错误"
+//	@Router			/todos/{id}/complete [post]
+func (h *TodoHandler) Complete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var input completeInput
+	if _, ok := bindOptionalJSON(c, &input); !ok {
+		return
+	}
+	note, ok := parseNote(c, input.Note)
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.WithContext(c.Request.Context()).Complete(id, time.Now().UTC(), actorName(c), note)
+	h.respondUpdate(c, item, err)
+}
+
+// Reopen 将待办事项重新打开（重置为未完成）。
+//
+//	@Summary		重新打开待办事项
+//	@Description	将指定 ID 的待办事项重置为未完成，并清空完成信息
+//	@Description	对未完成的待办事项重复调用不会改变其状态
+//	@Tags			todos
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int						true	"待办事项 ID"
+//	@Success		200	{object}	map[string]interface{}	"成功返回更新后的待办事项"
+//	@Failure		400	{object}	map[string]string		"请求参数错误"
+//	@Failure		404	{object}	map[string]string		"待办事项不存在"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/todos/{id}/reopen [post]
+func (h *TodoHandler) Reopen(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.WithContext(c.Request.Context()).Reopen(id, time.Now().UTC())
+	h.respondUpdate(c, item, err)
+}
+
+// respondUpdate 统一处理完成状态变更后的响应，并广播变更后的状态。
+func (h *TodoHandler) respondUpdate(c *gin.Context, item models.TodoItem, err error) {
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			RespondError(c, http.StatusNotFound, "todo not found")
 			return
 		}
-		RespondError(c, http.StatusInternalServerError, "toggle complete failed")
+		RespondError(c, http.StatusInternalServerError, "update todo failed")
 		return
 	}
 
+	eventType := events.TodoReopened
+	if item.IsCompleted {
+		eventType = events.TodoCompleted
+	}
+	h.broadcaster.Publish(eventType, item)
 	respondOK(c, toTodoResponse(item))
 }
 
+// bindOptionalJSON 解析可选的 JSON 请求体，空请求体不视为错误，此时 present 为 false。
+// 解析失败时已经写入 400 响应，ok 为 false。
+func bindOptionalJSON(c *gin.Context, obj any) (present, ok bool) {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, true
+		}
+		RespondError(c, http.StatusBadRequest, "invalid request body")
+		return false, false
+	}
+	return true, true
+}
+
+// parseNote 规范化完成备注并检查长度。
+// 超出长度限制时已经写入 400 响应，返回 false。
+func parseNote(c *gin.Context, raw string) (string, bool) {
+	note := strings.TrimSpace(raw)
+	if utf8.RuneCountInString(note) > maxCompletionNoteLength {
+		RespondError(c, http.StatusBadRequest, "note is too long")
+		return "", false
+	}
+	return note, true
+}
+
 // Delete 删除待办事项。
 //
 //	@Summary		删除待办事项
@@ -174,19 +388,23 @@ func (h *TodoHandler) ToggleComplete(c *gin.Context) {
 //	@Tags			todos
 //	@Accept			json
 //	@Produce		json
+//	@Security		BearerAuth
 //	@Param			id	path		int						true	"待办事项 ID"
 //	@Success		200	{object}	map[string]string    	"成功删除"
 //	@Failure		400	{object}	map[string]string		"请求参数错误"
 //	@Failure		404	{object}	map[string]string		"待办事项不存在"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
 //	@Failure		500	{object}	map[string]string		"服务器内部错误"
-//	@Router			/{id} [delete]
+//	@Router			/todos/{id} [delete]
 func (h *TodoHandler) Delete(c *gin.Context) {
 	id, ok := parseID(c)
 	if !ok {
 		return
 	}
 
-	if err := h.repo.Delete(id); err != nil {
+	item, err := h.repo.WithContext(c.Request.Context()).Delete(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			RespondError(c, http.StatusNotFound, "todo not found")
 			return
@@ -195,9 +413,152 @@ func (h *TodoHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	h.broadcaster.Publish(events.TodoDeleted, item)
 	respondOK(c, "ok")
 }
 
+// ListEvents 获取单个待办事项的 Webhook 事件时间线。
+//
+//	@Summary		获取待办事项事件时间线
+//	@Description	返回指定待办事项收到的所有 Webhook 投递记录，按接收时间倒序排列
+//	@Tags			todos
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		int						true	"待办事项 ID"
+//	@Success		200		{object}	map[string]interface{}	"成功返回事件列表"
+//	@Failure		400		{object}	map[string]string		"请求参数错误"
+//	@Failure		404		{object}	map[string]string		"待办事项不存在"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		500		{object}	map[string]string		"服务器内部错误"
+//	@Router			/todos/{id}/events [get]
+func (h *TodoHandler) ListEvents(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	timeline, err := h.repo.WithContext(c.Request.Context()).ListEvents(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "todo not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "list todo events failed")
+		return
+	}
+
+	response := make([]TodoEventResponse, 0, len(timeline))
+	for _, event := range timeline {
+		response = append(response, toTodoEventResponse(event))
+	}
+	respondOK(c, response)
+}
+
+// parseTodoFilter 将查询参数解析为 repo.TodoFilter。
+// 参数不合法时已经写入 400 响应，返回 false。
+func parseTodoFilter(c *gin.Context) (repo.TodoFilter, bool) {
+	filter := repo.TodoFilter{
+		PathPrefix:  c.Query("pathPrefix"),
+		Search:      strings.TrimSpace(c.Query("q")),
+		ProjectID:   c.Query("projectId"),
+		Environment: c.Query("environment"),
+		Cursor:      c.Query("cursor"),
+		Desc:        true,
+	}
+
+	// source 参数存在但为空时，只返回没有来源标记的待办事项
+	if source, ok := c.GetQuery("source"); ok {
+		filter.Source = &source
+	}
+
+	switch c.Query("status") {
+	case "", "all":
+	case "open":
+		completed := false
+		filter.Completed = &completed
+	case "completed":
+		completed := true
+		filter.Completed = &completed
+	default:
+		RespondError(c, http.StatusBadRequest, "invalid status")
+		return repo.TodoFilter{}, false
+	}
+
+	if raw := c.Query("sort"); raw != "" {
+		sort, ok := todoSortFields[raw]
+		if !ok {
+			RespondError(c, http.StatusBadRequest, "invalid sort")
+			return repo.TodoFilter{}, false
+		}
+		filter.Sort = sort
+	}
+
+	switch c.Query("order") {
+	case "", "desc":
+	case "asc":
+		filter.Desc = false
+	default:
+		RespondError(c, http.StatusBadRequest, "invalid order")
+		return repo.TodoFilter{}, false
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			RespondError(c, http.StatusBadRequest, "invalid limit")
+			return repo.TodoFilter{}, false
+		}
+		filter.Limit = limit
+	}
+
+	// 依次解析四个时间范围参数
+	ranges := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"createdFrom", &filter.CreatedFrom},
+		{"createdTo", &filter.CreatedTo},
+		{"completedFrom", &filter.CompletedFrom},
+		{"completedTo", &filter.CompletedTo},
+	}
+	for _, r := range ranges {
+		raw := c.Query(r.name)
+		if raw == "" {
+			continue
+		}
+		value, err := parseQueryTime(raw)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "invalid "+r.name)
+			return repo.TodoFilter{}, false
+		}
+		*r.target = &value
+	}
+
+	return filter, true
+}
+
+// parseQueryTime 解析查询参数中的时间，支持 RFC3339 与 YYYY-MM-DD（按 UTC 零点处理）。
+func parseQueryTime(raw string) (time.Time, error) {
+	if value, err := time.Parse(time.RFC3339, raw); err == nil {
+		return value, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+// actorName 返回当前操作者的名称，用于记录谁完成了待办事项。
+// 关闭认证时没有身份信息，返回空字符串。
+func actorName(c *gin.Context) string {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return ""
+	}
+	if principal.Name != "" {
+		return principal.Name
+	}
+	return principal.Subject
+}
+
 // parseID 辅助函数：从 URL 路径参数中解析 uint 类型的 ID。
 // 示例：/api/todos/123 -> 123
 func parseID(c *gin.Context) (uint, bool) {
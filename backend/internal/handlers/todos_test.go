@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUpdateCompletion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	todos := repo.NewTodoRepository(testdb.SQLite(t))
+	handler := NewTodoHandler(todos, events.NewBroadcaster(0))
+	engine := gin.New()
+	engine.PATCH("/todos/:id", handler.Update)
+
+	item, err := todos.Create(repo.TodoFields{SecretPath: "/db"}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	path := "/todos/" + strconv.FormatUint(uint64(item.ID), 10)
+
+	// patch 发送请求，返回状态码与响应中的完成状态
+	patch := func(body string) (int, bool) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var resp struct {
+			Data struct {
+				IsCompleted bool `json:"isCompleted"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data.IsCompleted
+	}
+
+	steps := []struct {
+		body      string
+		status    int
+		completed bool
+	}{
+		{body: `{"isCompleted": true}`, status: http.StatusOK, completed: true},
+		{body: `{"isCompleted": true, "note": "done"}`, status: http.StatusOK, completed: true},
+		// 携带请求体但没有 isCompleted 不能被当作切换
+		{body: `{"note": "done"}`, status: http.StatusBadRequest},
+		{body: `{}`, status: http.StatusBadRequest},
+		{body: `{"isCompleted": false}`, status: http.StatusOK, completed: false},
+		// 空请求体保持旧客户端的切换行为
+		{body: ``, status: http.StatusOK, completed: true},
+		{body: ``, status: http.StatusOK, completed: false},
+		{body: `{"isCompleted":`, status: http.StatusBadRequest},
+	}
+	for i, step := range steps {
+		status, completed := patch(step.body)
+		if status != step.status || (status == http.StatusOK && completed != step.completed) {
+			t.Fatalf("step %d PATCH %q = %d completed=%v, want %d completed=%v", i, step.body, status, completed, step.status, step.completed)
+		}
+	}
+}
+
+func TestCompletionRecordsActorAndNote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	todos := repo.NewTodoRepository(testdb.SQLite(t))
+	handler := NewTodoHandler(todos, events.NewBroadcaster(0))
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		auth.SetPrincipal(c, auth.Principal{Subject: "oidc:alice", Name: "alice"})
+	})
+	engine.POST("/todos/:id/complete", handler.Complete)
+	engine.POST("/todos/:id/reopen", handler.Reopen)
+
+	item, err := todos.Create(repo.TodoFields{SecretPath: "/db"}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	path := "/todos/" + strconv.FormatUint(uint64(item.ID), 10)
+
+	// post 发送请求，返回状态码与响应中的待办事项
+	post := func(action, body string) (int, TodoResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path+"/"+action, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var resp struct {
+			Data TodoResponse `json:"data"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Data
+	}
+
+	// 备注过长或请求体格式错误时不改变状态
+	for _, body := range []string{`{"note": "` + strings.Repeat("字", maxCompletionNoteLength+1) + `"}`, `{"note":`} {
+		if status, _ := post("complete", body); status != http.StatusBadRequest {
+			t.Fatalf("complete %.20q = %d, want 400", body, status)
+		}
+	}
+
+	// 重复标记完成是幂等的，保留第一次的操作者与备注
+	for _, note := range []string{"  updated api and worker  ", "retry"} {
+		status, done := post("complete", `{"note": "`+note+`"}`)
+		if status != http.StatusOK || !done.IsCompleted || done.CompletedBy != "alice" || done.CompletionNote != "updated api and worker" {
+			t.Fatalf("complete = %d %+v, want completed by alice with the first trimmed note", status, done)
+		}
+	}
+
+	// 重新打开时清空完成记录，重复调用结果相同
+	for i := 0; i < 2; i++ {
+		status, reopened := post("reopen", ``)
+		if status != http.StatusOK || reopened.IsCompleted || reopened.CompletedBy != "" || reopened.CompletionNote != "" {
+			t.Fatalf("reopen = %d %+v, want open with completion fields cleared", status, reopened)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package handlers 包含 API Token 管理相关的处理逻辑。
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/repo"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TokenHandler 处理 API Token 的增删查请求。
+type TokenHandler struct {
+	repo *repo.TokenRepository
+}
+
+// NewTokenHandler 创建 TokenHandler 实例。
+func NewTokenHandler(repo *repo.TokenRepository) *TokenHandler {
+	return &TokenHandler{repo: repo}
+}
+
+// tokenInput 定义了创建 Token 接口的请求体结构。
+type tokenInput struct {
+	Name string `json:"name"`
+	// Role 可选 viewer / operator / admin，默认 viewer。
+	Role string `json:"role"`
+}
+
+// List 获取所有 API Token（不包含明文）。
+//
+//	@Summary		获取 API Token 列表
+//	@Description	返回所有 API Token 的元数据，Token 明文只在创建时返回一次
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"成功返回 Token 列表"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/tokens [get]
+func (h *TokenHandler) List(c *gin.Context) {
+	items, err := h.repo.List()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "list tokens failed")
+		return
+	}
+
+	response := make([]TokenResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, toTokenResponse(item))
+	}
+	respondOK(c, response)
+}
+
+// Create 创建一个新的 API Token。
+//
+//	@Summary		创建 API Token
+//	@Description	生成一个新的 API Token，响应中的 token 字段是明文，只返回这一次，请妥善保存
+//	@Description	role 可选 viewer / operator / admin，默认 viewer；需要 admin 角色
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			token	body		tokenInput				true	"Token 信息"
+//	@Success		200		{object}	map[string]interface{}	"成功返回创建的 Token（含明文）"
+//	@Failure		400		{object}	map[string]string		"请求参数错误"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		403		{object}	map[string]string		"权限不足"
+//	@Failure		500		{object}	map[string]string		"服务器内部错误"
+//	@Router			/tokens [post]
+func (h *TokenHandler) Create(c *gin.Context) {
+	var input tokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		RespondError(c, http.StatusBadRequest, "name is required")
+		return
+	}
+
+	role := auth.RoleViewer
+	if strings.TrimSpace(input.Role) != "" {
+		parsed, err := auth.ParseRole(input.Role)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, "role must be one of viewer, operator, admin")
+			return
+		}
+		role = parsed
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "generate token failed")
+		return
+	}
+
+	item, err := h.repo.Create(name, string(role), auth.HashToken(token), auth.DisplayPrefix(token), time.Now().UTC())
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "create token failed")
+		return
+	}
+
+	respondOK(c, CreatedTokenResponse{TokenResponse: toTokenResponse(item), Token: token})
+}
+
+// Delete 吊销（删除）API Token。
+//
+//	@Summary		吊销 API Token
+//	@Description	删除指定 ID 的 API Token，之后使用该 Token 的请求会被拒绝
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int						true	"Token ID"
+//	@Success		200	{object}	map[string]string		"成功删除"
+//	@Failure		400	{object}	map[string]string		"请求参数错误"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		404	{object}	map[string]string		"Token 不存在"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/tokens/{id} [delete]
+func (h *TokenHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "token not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "delete token failed")
+		return
+	}
+
+	respondOK(c, "ok")
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"backend/internal/auth"
+	"backend/internal/models"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := testdb.SQLite(t)
+	handler := NewTokenHandler(repo.NewTokenRepository(database))
+	engine := gin.New()
+	engine.GET("/tokens", handler.List)
+	engine.POST("/tokens", handler.Create)
+	engine.DELETE("/tokens/:id", handler.Delete)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"name": " "}`, `{"name": "ci", "role": "root"}`, `{"name":`} {
+		if w := do(http.MethodPost, "/tokens", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("POST %s = %d, want 400", body, w.Code)
+		}
+	}
+
+	w := do(http.MethodPost, "/tokens", `{"name": "ci"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST = %d %s, want 200", w.Code, w.Body)
+	}
+	var created struct {
+		Data CreatedTokenResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	token := created.Data.Token
+	if !strings.HasPrefix(token, "itn_") || created.Data.Role != string(auth.RoleViewer) || !strings.HasPrefix(token, created.Data.Prefix) {
+		t.Fatalf("created token = %+v, want a viewer itn_ token", created.Data)
+	}
+
+	// 数据库中只保存哈希与展示用的前缀，任何一列都不包含明文
+	var stored models.APIToken
+	if err := database.First(&stored, created.Data.ID).Error; err != nil {
+		t.Fatalf("find token: %v", err)
+	}
+	if stored.TokenHash != auth.HashToken(token) || stored.Prefix == token || len(stored.Prefix) >= len(token) {
+		t.Fatalf("stored token = %+v, want only the hash and a short prefix", stored)
+	}
+
+	// 列表不返回明文
+	if w := do(http.MethodGet, "/tokens", ""); w.Code != http.StatusOK || strings.Contains(w.Body.String(), token) {
+		t.Fatalf("GET = %d %s, want 200 without the plaintext token", w.Code, w.Body)
+	}
+
+	path := "/tokens/" + strconv.FormatUint(uint64(created.Data.ID), 10)
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusOK {
+		t.Fatalf("DELETE = %d, want 200", w.Code)
+	}
+	if w := do(http.MethodDelete, path, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d, want 404", w.Code)
+	}
+}
@@ -2,15 +2,31 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"backend/internal/events"
+	"backend/internal/metrics"
+	"backend/internal/models"
+	"backend/internal/notify"
+	"backend/internal/replay"
 	"backend/internal/repo"
 	"backend/internal/signature"
+	"backend/internal/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 const (
@@ -24,21 +40,41 @@ const (
 
 // WebhookHandler 专门处理 Webhook 请求。
 type WebhookHandler struct {
-	repo   *repo.TodoRepository
-	secret string // 用于验证签名的密钥
+	repo         *repo.TodoRepository
+	sources      *repo.WebhookSourceRepository   // 按来源区分的 Webhook 地址与 Secret
+	secrets      atomic.Pointer[[]signature.Key] // 用于验证签名的密钥，任意一个匹配即可；重新加载配置时整体替换
+	replayWindow time.Duration                   // 签名时间戳的有效窗口
+	replay       replay.Cache                    // 记录窗口期内处理过的签名，拒绝重放
+	outbox       *notify.Outbox                  // 通知发件箱，未启用推送时为 nil
+	broadcaster  *events.Broadcaster             // 入库成功后广播变更事件
 }
 
 // NewWebhookHandler 创建 WebhookHandler 实例。
-func NewWebhookHandler(repo *repo.TodoRepository, secret string) *WebhookHandler {
-	return &WebhookHandler{repo: repo, secret: strings.TrimSpace(secret)}
+// outbox 为 nil 表示未启用推送，Webhook 只更新待办事项。
+func NewWebhookHandler(repo *repo.TodoRepository, sources *repo.WebhookSourceRepository, secrets []signature.Key, replayWindow time.Duration, replayCache replay.Cache, outbox *notify.Outbox, broadcaster *events.Broadcaster) *WebhookHandler {
+	h := &WebhookHandler{
+		repo:         repo,
+		sources:      sources,
+		replayWindow: replayWindow,
+		replay:       replayCache,
+		outbox:       outbox,
+		broadcaster:  broadcaster,
+	}
+	h.SetSecrets(secrets)
+	return h
+}
+
+// SetSecrets 替换 /api/todos/webhook 使用的 Secret 列表，用于不重启服务轮换 Secret。
+// 正在处理的请求继续使用替换前的列表。
+func (h *WebhookHandler) SetSecrets(secrets []signature.Key) {
+	h.secrets.Store(&secrets)
 }
 
 // webhookPayload 定义了 Infisical Webhook 的 JSON 载荷结构。
 type webhookPayload struct {
 	Event   string `json:"event"`
 	Project struct {
-		SecretPath string `json:"secretPath"`
-		// 以下字段目前未使用，但保留方便将来扩展
+		SecretPath   string `json:"secretPath"`
 		ProjectID    string `json:"projectId"`
 		ProjectName  string `json:"projectName"`
 		Environment  string `json:"environment"`
@@ -51,26 +87,75 @@ type webhookPayload struct {
 // Handle 处理 Webhook 请求的主要逻辑。
 //
 //	@Summary		接收 Infisical Webhook
-//	@Description	接收来自 Infisical 的 Webhook 通知并创建或更新待办事项
+//	@Description	接收来自 Infisical 的 Webhook 通知并创建或更新待办事项，配置了 Apprise 时同时推送提醒
 //	@Description	注意：此接口使用 HMAC-SHA256 签名验证，需要在 X-Infisical-Signature 头中提供正确的签名
-//	@Description	签名格式：t=<timestamp>,v1=<signature>，其中 signature = HMAC-SHA256(secret, timestamp + "." + requestBody)
-//	@Description	secret 通过环境变量 INFISICAL_WEBHOOK_SECRET 配置
+//	@Description	签名格式：t=<timestamp>;sha256=<signature>，其中 signature = HMAC-SHA256(secret, requestBody)，只覆盖原始请求体，t= 不参与签名
+//	@Description	secret 通过环境变量 INFISICAL_WEBHOOK_SECRET 或 INFISICAL_WEBHOOK_SECRETS（多个，用于轮换）配置
 //	@Tags			webhook
 //	@Accept			json
 //	@Produce		json
-//	@Param			X-Infisical-Signature	header		string					true	"Webhook 签名（格式：t=timestamp,v1=signature）"
+//	@Param			X-Infisical-Signature	header		string					true	"Webhook 签名（格式：t=timestamp;sha256=signature）"
 //	@Param			payload					body		webhookPayload			true	"Webhook 载荷"
 //	@Success		200						{object}	map[string]interface{}	"成功处理 Webhook"
 //	@Failure		400						{object}	map[string]string		"请求参数错误"
-//	@Failure		401						{object}	map[string]string		"签名验证失败"
+//	@Failure		401						{object}	map[string]string		"签名验证失败、载荷时间戳过期或请求被重放"
 //	@Failure		500						{object}	map[string]string		"服务器内部错误"
-//	@Router			/webhook [post]
+//	@Router			/todos/webhook [post]
 func (h *WebhookHandler) Handle(c *gin.Context) {
+	h.handle(c, *h.secrets.Load(), nil)
+}
+
+// HandleSource 处理发往某个 Webhook 来源的请求。
+//
+//	@Summary		接收指定来源的 Infisical Webhook
+//	@Description	与 /todos/webhook 相同，但使用该来源自己的 Secret 验证签名，并按来源的事件过滤规则处理
+//	@Description	载荷中没有 projectName 时使用来源的默认项目名，创建的待办事项会标记来源
+//	@Tags			webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			source					path		string					true	"Webhook 来源标识"
+//	@Param			X-Infisical-Signature	header		string					true	"Webhook 签名（格式：t=timestamp;sha256=signature）"
+//	@Param			payload					body		webhookPayload			true	"Webhook 载荷"
+//	@Success		200						{object}	map[string]interface{}	"成功处理 Webhook"
+//	@Failure		400						{object}	map[string]string		"请求参数错误"
+//	@Failure		401						{object}	map[string]string		"来源不存在、签名验证失败、载荷时间戳过期或请求被重放"
+//	@Failure		500						{object}	map[string]string		"服务器内部错误"
+//	@Router			/webhooks/{source} [post]
+func (h *WebhookHandler) HandleSource(c *gin.Context) {
+	// 来源不存在时与签名错误一样返回 401，不向调用方暴露哪些来源存在
+	source, err := h.sources.FindBySlug(c.Param("source"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.ObserveWebhook(metrics.EventUnknown, metrics.WebhookResultUnauthorized, metrics.ReasonUnknownSource)
+			RespondUnauthorized(c, "unknown webhook source")
+			return
+		}
+		metrics.ObserveWebhook(metrics.EventUnknown, metrics.WebhookResultDBError, "")
+		RespondError(c, http.StatusInternalServerError, "load webhook source failed")
+		return
+	}
+
+	h.handle(c, []signature.Key{{ID: "source:" + source.Slug, Secret: source.Secret}}, &source)
+}
+
+// handle 是两个 Webhook 地址共用的处理流程。
+// keys 是用于验证签名的 Secret；source 是请求所属的来源，来自默认地址时为 nil。
+func (h *WebhookHandler) handle(c *gin.Context, keys []signature.Key, source *models.WebhookSource) {
+	// 返回前记录本次投递的结果，供 /metrics 统计。
+	// 各个分支只需要修改 result 与 reason；签名验证通过并解析载荷之后 event 才有意义。
+	event, result, reason := metrics.EventUnknown, metrics.WebhookResultOK, ""
+	defer func() { metrics.ObserveWebhook(event, result, reason) }()
+	reject := func(metricReason, message string) {
+		result, reason = metrics.WebhookResultUnauthorized, metricReason
+		RespondUnauthorized(c, message)
+	}
+
 	// 1. 获取原始请求体 (Raw Data)
 	// 验证签名需要原始的字节流，而不是解析后的 JSON 对象。
 	// 任何对 JSON 的微小改动（如空格）都会导致签名验证失败。
 	bodyBytes, err := c.GetRawData()
 	if err != nil {
+		result = metrics.WebhookResultInvalidPayload
 		RespondError(c, http.StatusBadRequest, "read body failed")
 		return
 	}
@@ -79,67 +164,212 @@ func (h *WebhookHandler) Handle(c *gin.Context) {
 	// Infisical 签名是基于原始字节计算的，任何修改都会导致签名不匹配
 	bodyText := string(bodyBytes)
 	if strings.TrimSpace(bodyText) == "" {
+		result = metrics.WebhookResultInvalidPayload
 		RespondError(c, http.StatusBadRequest, "empty body")
 		return
 	}
 
 	// 2. 检查系统是否配置了 Webhook Secret
-	if strings.TrimSpace(h.secret) == "" {
-		respondUnauthorized(c, "missing webhook secret")
+	if len(keys) == 0 {
+		reject(metrics.ReasonMissingSecret, "missing webhook secret")
 		return
 	}
 
 	// 3. 获取签名头
 	signatureHeaderValue := strings.TrimSpace(c.GetHeader(signatureHeader))
 	if signatureHeaderValue == "" {
-		respondUnauthorized(c, "missing signature header")
+		reject(metrics.ReasonMissingSignature, "missing signature header")
 		return
 	}
 
 	// 4. 验证签名
 	// 调用 signature 包的逻辑，确保请求确实来自 Infisical 且未被篡改。
-	if err := signature.VerifySignature(bodyText, signatureHeaderValue, h.secret, time.Now().UTC()); err != nil {
-		respondUnauthorized(c, "invalid signature")
+	now := time.Now().UTC()
+	// 配置了多个 Secret 时，任意一个未过期的 Secret 匹配即可，日志中记录是哪一个，便于确认轮换进度。
+	keyID, digest, err := verifySignature(c.Request.Context(), bodyText, signatureHeaderValue, keys, now, h.replayWindow)
+	if err != nil {
+		if errors.Is(err, signature.ErrNoActiveKey) {
+			reject(metrics.ReasonSecretsExpired, "all webhook secrets expired")
+			return
+		}
+		reject(metrics.ReasonInvalidSignature, "invalid signature")
 		return
 	}
+	slog.InfoContext(c.Request.Context(), "Webhook 签名验证通过", "key_id", keyID)
 
 	// 5. 解析 JSON 载荷
 	var payload webhookPayload
 	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		result = metrics.WebhookResultInvalidPayload
 		RespondError(c, http.StatusBadRequest, "invalid payload")
 		return
 	}
 
-	// 6. 过滤事件类型
-	if !isSupportedEvent(payload.Event) {
+	// 6. 校验载荷时间戳并拒绝重放
+	// 签名头中的 t= 不受签名保护，截获请求的一方可以换上新的时间戳，
+	// 因此以受签名保护的 payload.timestamp 判断请求是否过期。
+	signedAt, err := signature.CheckTimestamp(payload.Timestamp, now, h.replayWindow)
+	if err != nil {
+		reject(metrics.ReasonStaleTimestamp, "stale payload timestamp")
+		return
+	}
+	// 签名正确但已经处理过，说明请求被截获后重新发送。
+	// 摘要一直保留到载荷时间戳离开窗口，之后同一个请求会因为时间戳过期而被拒绝。
+	fresh, err := h.replay.Remember(digest, now, signedAt.Add(h.replayWindow))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "检查 Webhook 重放失败", "error", err)
+		result = metrics.WebhookResultDBError
+		RespondError(c, http.StatusInternalServerError, "replay check failed")
+		return
+	}
+	if !fresh {
+		reject(metrics.ReasonReplayed, "replayed signature")
+		return
+	}
+
+	event = metrics.EventOther
+	if isSupportedEvent(payload.Event) {
+		event = payload.Event
+	}
+
+	// 7. 过滤事件类型，来源可以进一步限制接受哪些事件
+	if !isSupportedEvent(payload.Event) || (source != nil && !source.AllowsEvent(payload.Event)) {
+		result = metrics.WebhookResultIgnored
 		respondOK(c, "ignored")
 		return
 	}
 
-	// 测试事件直接返回 OK
+	// 载荷中没有项目名时使用来源的默认项目名，通知中也会用到
+	sourceSlug := ""
+	if source != nil {
+		sourceSlug = source.Slug
+		if strings.TrimSpace(payload.Project.ProjectName) == "" {
+			payload.Project.ProjectName = source.ProjectLabel
+		}
+	}
+
+	// 请求没有生效时删除签名记录，允许 Infisical 重试同一个请求
+	fail := func(message string) {
+		result = metrics.WebhookResultDBError
+		if err := h.replay.Forget(digest); err != nil {
+			slog.WarnContext(c.Request.Context(), "删除 Webhook 签名记录失败", "error", err)
+		}
+		RespondError(c, http.StatusInternalServerError, message)
+	}
+
+	// 使用 context.WithoutCancel：Infisical 断开连接时不中断写入，只沿用 ctx 中的链路追踪信息。
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	// 测试事件不入库，但仍推送一条测试通知，方便验证通知渠道配置
 	if payload.Event == eventTest {
+		if h.outbox != nil {
+			msg, err := newNotification(payload)
+			if err == nil {
+				err = h.outbox.Notify(ctx, msg)
+			}
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "测试通知入队失败", "error", err)
+				fail("enqueue notification failed")
+				return
+			}
+		}
 		respondOK(c, "ok")
 		return
 	}
 
-	// 7. 处理业务逻辑
+	// 8. 处理业务逻辑
 	// 提取 secretPath，如果没有则默认为根路径 "/"
 	secretPath := strings.TrimSpace(payload.Project.SecretPath)
 	if secretPath == "" {
+		result = metrics.WebhookResultInvalidPayload
 		RespondError(c, http.StatusBadRequest, "secretPath in webhook payload is required and cannot be empty")
 		return
 	}
 
+	// 启用推送时，通知与待办事项、事件记录在同一个事务中写入发件箱：
+	// 只要 Webhook 返回 200，提醒就已经持久化，不会因为进程崩溃或写库失败而丢失。
+	var notification *repo.OutboxMessage
+	if h.outbox != nil {
+		msg, err := newNotification(payload)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "渲染通知模板失败", "error", err)
+			fail("render notification failed")
+			return
+		}
+		outboxMsg := msg.OutboxMessage(ctx)
+		notification = &outboxMsg
+	}
+
 	// 更新或插入 Todo 项
-	item, err := h.repo.UpsertFromWebhook(secretPath, time.Now().UTC())
+	// 项目 + 环境 + 路径 共同决定 Todo 的身份，避免不同项目的同名路径互相覆盖。
+	item, created, err := h.repo.WithContext(ctx).UpsertFromWebhook(repo.TodoFields{
+		ProjectID:    strings.TrimSpace(payload.Project.ProjectID),
+		ProjectName:  strings.TrimSpace(payload.Project.ProjectName),
+		Environment:  strings.TrimSpace(payload.Project.Environment),
+		SecretPath:   secretPath,
+		SecretName:   strings.TrimSpace(payload.Project.SecretName),
+		ReminderNote: strings.TrimSpace(payload.Project.ReminderNote),
+		Source:       sourceSlug,
+	}, repo.WebhookEvent{
+		EventType:          payload.Event,
+		InfisicalTimestamp: payload.Timestamp,
+		SourceIP:           c.ClientIP(),
+		BodyHash:           hashBody(bodyBytes),
+	}, notification, now)
 	if err != nil {
-		RespondError(c, http.StatusInternalServerError, "upsert todo failed")
+		slog.ErrorContext(c.Request.Context(), "Webhook 写入待办事项失败", "secret_path", secretPath, "error", err)
+		fail("upsert todo failed")
 		return
 	}
 
+	// 9. 广播变更并唤醒发件箱 Worker
+	eventType := events.TodoReset
+	if created {
+		eventType = events.TodoCreated
+	}
+	slog.InfoContext(c.Request.Context(), "Webhook 已处理", "event", eventType, "todo_id", item.ID, "secret_path", secretPath, "source", sourceSlug)
+	h.broadcaster.Publish(eventType, item)
+	if h.outbox != nil {
+		h.outbox.Wake()
+	}
+
 	respondOK(c, toTodoResponse(item))
 }
 
+// newNotification 根据 Webhook 载荷渲染通知。
+// 通知内容不包含待办事项 ID，写入发件箱时再关联到 Upsert 得到的记录。
+func newNotification(payload webhookPayload) (notify.Message, error) {
+	secretPath := strings.TrimSpace(payload.Project.SecretPath)
+	if secretPath == "" {
+		secretPath = "/"
+	}
+
+	return notify.NewMessage(notify.MessageData{
+		Event:        payload.Event,
+		ProjectName:  strings.TrimSpace(payload.Project.ProjectName),
+		Environment:  strings.TrimSpace(payload.Project.Environment),
+		SecretPath:   secretPath,
+		SecretName:   strings.TrimSpace(payload.Project.SecretName),
+		ReminderNote: strings.TrimSpace(payload.Project.ReminderNote),
+	})
+}
+
+// verifySignature 调用 signature.VerifySignature，并把这一步记录为链路追踪中的一个 Span。
+// Span 只记录匹配的 Secret ID 与失败原因，不记录签名与 Secret 本身。
+func verifySignature(ctx context.Context, body, header string, keys []signature.Key, now time.Time, window time.Duration) (keyID, digest string, err error) {
+	_, span := tracing.Tracer().Start(ctx, "signature.VerifySignature",
+		trace.WithAttributes(attribute.Int("webhook.key_count", len(keys))))
+	defer span.End()
+
+	keyID, digest, err = signature.VerifySignature(body, header, keys, now, window)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return "", "", err
+	}
+	span.SetAttributes(attribute.String("webhook.key_id", keyID))
+	return keyID, digest, nil
+}
+
 func isSupportedEvent(event string) bool {
 	switch event {
 	case eventSecretsModified, eventTest:
@@ -148,3 +378,9 @@ func isSupportedEvent(event string) bool {
 		return false
 	}
 }
+
+// hashBody 计算原始请求体的 SHA-256 十六进制摘要，用于事件时间线。
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
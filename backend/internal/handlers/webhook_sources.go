@@ -0,0 +1,290 @@
+// Package handlers 包含 Webhook 来源管理相关的处理逻辑。
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repo"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// sourceSlugPattern 限制来源标识只能包含小写字母、数字、连字符和下划线，方便直接放进 URL。
+var sourceSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// minSourceSecretLength 是手动指定的来源 Secret 的最小长度。
+const minSourceSecretLength = 16
+
+// WebhookSourceHandler 处理 Webhook 来源的增删改查请求。
+type WebhookSourceHandler struct {
+	repo *repo.WebhookSourceRepository
+}
+
+// NewWebhookSourceHandler 创建 WebhookSourceHandler 实例。
+func NewWebhookSourceHandler(repo *repo.WebhookSourceRepository) *WebhookSourceHandler {
+	return &WebhookSourceHandler{repo: repo}
+}
+
+// webhookSourceInput 定义了创建 Webhook 来源接口的请求体结构。
+type webhookSourceInput struct {
+	// Slug 是来源在 URL 中的标识，创建后不能修改。
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+	// Secret 为空时自动生成。
+	Secret       string   `json:"secret"`
+	ProjectLabel string   `json:"projectLabel"`
+	Events       []string `json:"events"`
+}
+
+// webhookSourceUpdateInput 定义了修改 Webhook 来源接口的请求体结构，未提供的字段保持不变。
+type webhookSourceUpdateInput struct {
+	Name         *string   `json:"name"`
+	Secret       *string   `json:"secret"`
+	ProjectLabel *string   `json:"projectLabel"`
+	Events       *[]string `json:"events"`
+}
+
+// List 获取所有 Webhook 来源（不包含 Secret）。
+//
+//	@Summary		获取 Webhook 来源列表
+//	@Description	返回所有 Webhook 来源，Secret 只在创建时返回一次
+//	@Tags			webhook-sources
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Success		200	{object}	map[string]interface{}	"成功返回来源列表"
+//	@Failure		401	{object}	map[string]string		"未认证"
+//	@Failure		403	{object}	map[string]string		"权限不足"
+//	@Failure		500	{object}	map[string]string		"服务器内部错误"
+//	@Router			/webhook-sources [get]
+func (h *WebhookSourceHandler) List(c *gin.Context) {
+	items, err := h.repo.List()
+	if err != nil {
+		RespondError(c, http.StatusInternalServerError, "list webhook sources failed")
+		return
+	}
+
+	response := make([]WebhookSourceResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, toWebhookSourceResponse(item))
+	}
+	respondOK(c, response)
+}
+
+// Create 创建一个新的 Webhook 来源。
+//
+//	@Summary		创建 Webhook 来源
+//	@Description	创建一个新的 Webhook 来源，Infisical 中的 Webhook 地址填写响应中的 url
+//	@Description	secret 为空时自动生成，响应中的 secret 字段只返回这一次，请填写到 Infisical 中
+//	@Description	events 为空表示接受所有支持的事件（secrets.modified、test）；需要 admin 角色
+//	@Tags			webhook-sources
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			source	body		webhookSourceInput		true	"来源信息"
+//	@Success		200		{object}	map[string]interface{}	"成功返回创建的来源（含 Secret）"
+//	@Failure		400		{object}	map[string]string		"请求参数错误"
+//	@Failure		401		{object}	map[string]string		"未认证"
+//	@Failure		403		{object}	map[string]string		"权限不足"
+//	@Failure		409		{object}	map[string]string		"来源标识已存在"
+//	@Failure		500		{object}	map[string]string		"服务器内部错误"
+//	@Router			/webhook-sources [post]
+func (h *WebhookSourceHandler) Create(c *gin.Context) {
+	var input webhookSourceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	if !sourceSlugPattern.MatchString(slug) {
+		RespondError(c, http.StatusBadRequest, "slug must be 1-64 lowercase letters, digits, '-' or '_'")
+		return
+	}
+
+	secret := strings.TrimSpace(input.Secret)
+	if secret == "" {
+		generated, err := generateSourceSecret()
+		if err != nil {
+			RespondError(c, http.StatusInternalServerError, "generate secret failed")
+			return
+		}
+		secret = generated
+	} else if len(secret) < minSourceSecretLength {
+		RespondError(c, http.StatusBadRequest, "secret must be at least 16 characters")
+		return
+	}
+
+	events, ok := parseSourceEvents(c, input.Events)
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.Create(slug, repo.WebhookSourceFields{
+		Name:         strings.TrimSpace(input.Name),
+		Secret:       secret,
+		ProjectLabel: strings.TrimSpace(input.ProjectLabel),
+		Events:       events,
+	}, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			RespondError(c, http.StatusConflict, "webhook source already exists")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "create webhook source failed")
+		return
+	}
+
+	respondOK(c, CreatedWebhookSourceResponse{WebhookSourceResponse: toWebhookSourceResponse(item), Secret: secret})
+}
+
+// Update 修改 Webhook 来源。
+//
+//	@Summary		修改 Webhook 来源
+//	@Description	修改来源的名称、Secret、默认项目名或事件过滤规则，未提供的字段保持不变
+//	@Description	修改 Secret 后旧 Secret 立即失效，请同时更新 Infisical 中的配置；需要 admin 角色
+//	@Tags			webhook-sources
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id		path		int							true	"来源 ID"
+//	@Param			source	body		webhookSourceUpdateInput	true	"要修改的字段"
+//	@Success		200		{object}	map[string]interface{}		"成功返回修改后的来源"
+//	@Failure		400		{object}	map[string]string			"请求参数错误"
+//	@Failure		401		{object}	map[string]string			"未认证"
+//	@Failure		403		{object}	map[string]string			"权限不足"
+//	@Failure		404		{object}	map[string]string			"来源不存在"
+//	@Failure		500		{object}	map[string]string			"服务器内部错误"
+//	@Router			/webhook-sources/{id} [patch]
+func (h *WebhookSourceHandler) Update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	var input webhookSourceUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		RespondError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	item, err := h.repo.Get(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "webhook source not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "get webhook source failed")
+		return
+	}
+
+	fields := repo.WebhookSourceFields{
+		Name:         item.Name,
+		Secret:       item.Secret,
+		ProjectLabel: item.ProjectLabel,
+		Events:       item.Events,
+	}
+	if input.Name != nil {
+		fields.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Secret != nil {
+		secret := strings.TrimSpace(*input.Secret)
+		if len(secret) < minSourceSecretLength {
+			RespondError(c, http.StatusBadRequest, "secret must be at least 16 characters")
+			return
+		}
+		fields.Secret = secret
+	}
+	if input.ProjectLabel != nil {
+		fields.ProjectLabel = strings.TrimSpace(*input.ProjectLabel)
+	}
+	if input.Events != nil {
+		events, ok := parseSourceEvents(c, *input.Events)
+		if !ok {
+			return
+		}
+		fields.Events = events
+	}
+
+	updated, err := h.repo.Update(id, fields, time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "webhook source not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "update webhook source failed")
+		return
+	}
+
+	respondOK(c, toWebhookSourceResponse(updated))
+}
+
+// Delete 删除 Webhook 来源。
+//
+//	@Summary		删除 Webhook 来源
+//	@Description	删除指定 ID 的 Webhook 来源，之后发往该来源地址的请求会被拒绝；已创建的待办事项保留来源标记
+//	@Tags			webhook-sources
+//	@Accept			json
+//	@Produce		json
+//	@Security		BearerAuth
+//	@Param			id	path		int					true	"来源 ID"
+//	@Success		200	{object}	map[string]string	"成功删除"
+//	@Failure		400	{object}	map[string]string	"请求参数错误"
+//	@Failure		401	{object}	map[string]string	"未认证"
+//	@Failure		403	{object}	map[string]string	"权限不足"
+//	@Failure		404	{object}	map[string]string	"来源不存在"
+//	@Failure		500	{object}	map[string]string	"服务器内部错误"
+//	@Router			/webhook-sources/{id} [delete]
+func (h *WebhookSourceHandler) Delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			RespondError(c, http.StatusNotFound, "webhook source not found")
+			return
+		}
+		RespondError(c, http.StatusInternalServerError, "delete webhook source failed")
+		return
+	}
+
+	respondOK(c, "ok")
+}
+
+// parseSourceEvents 校验事件过滤列表，返回逗号分隔的形式。校验失败时已写入错误响应。
+func parseSourceEvents(c *gin.Context, events []string) (string, bool) {
+	cleaned := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !isSupportedEvent(event) {
+			RespondError(c, http.StatusBadRequest, "events must only contain secrets.modified or test")
+			return "", false
+		}
+		cleaned = append(cleaned, event)
+	}
+	return strings.Join(cleaned, ","), true
+}
+
+// generateSourceSecret 生成一个随机的来源 Secret。
+func generateSourceSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// webhookSourcePath 返回来源的 Webhook 地址（相对于服务根地址）。
+func webhookSourcePath(item models.WebhookSource) string {
+	return "/api/webhooks/" + item.Slug
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/events"
+	"backend/internal/replay"
+	"backend/internal/repo"
+	"backend/internal/signature"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testWebhookSecret = "test-webhook-secret"
+	testReplayWindow  = 5 * time.Minute
+)
+
+// newWebhookTestRouter 创建只注册了默认 Webhook 地址的引擎，未启用推送。
+func newWebhookTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	database := testdb.SQLite(t)
+	handler := NewWebhookHandler(repo.NewTodoRepository(database), repo.NewWebhookSourceRepository(database), []signature.Key{{ID: "test", Secret: testWebhookSecret}},
+		testReplayWindow, replay.NewMemoryCache(testReplayWindow), nil, events.NewBroadcaster(0))
+
+	engine := gin.New()
+	engine.POST("/todos/webhook", handler.Handle)
+	return engine
+}
+
+// postWebhook 按 Infisical 的格式签名并发送请求：HMAC 只覆盖请求体，t= 不受签名保护。
+func postWebhook(engine *gin.Engine, body string, headerTime time.Time) int {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, "/todos/webhook", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, fmt.Sprintf("t=%d;sha256=%s", headerTime.UnixMilli(), hex.EncodeToString(mac.Sum(nil))))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+// webhookBody 返回载荷时间戳为 signedAt 的 secrets.modified 事件。
+func webhookBody(signedAt time.Time) string {
+	return fmt.Sprintf(`{"event":"secrets.modified","project":{"projectId":"p1","environment":"prod","secretPath":"/db"},"timestamp":%d}`, signedAt.UnixMilli())
+}
+
+func TestWebhookRejectsReplay(t *testing.T) {
+	engine := newWebhookTestRouter(t)
+	body := webhookBody(time.Now())
+
+	if code := postWebhook(engine, body, time.Now()); code != http.StatusOK {
+		t.Fatalf("first delivery = %d, want 200", code)
+	}
+	if code := postWebhook(engine, body, time.Now()); code != http.StatusUnauthorized {
+		t.Fatalf("replayed delivery = %d, want 401", code)
+	}
+}
+
+func TestWebhookRejectsStalePayloadWithFreshHeader(t *testing.T) {
+	engine := newWebhookTestRouter(t)
+
+	// 截获的旧请求换上新的 t= 之后签名仍然有效，必须按载荷中受签名保护的时间戳拒绝
+	body := webhookBody(time.Now().Add(-2 * testReplayWindow))
+	if code := postWebhook(engine, body, time.Now()); code != http.StatusUnauthorized {
+		t.Fatalf("stale payload with fresh header = %d, want 401", code)
+	}
+
+	// 缺少时间戳的载荷同样拒绝
+	if code := postWebhook(engine, `{"event":"secrets.modified","project":{"secretPath":"/db"}}`, time.Now()); code != http.StatusUnauthorized {
+		t.Fatalf("payload without timestamp = %d, want 401", code)
+	}
+}
+
+func TestWebhookSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := testdb.SQLite(t)
+	sources := repo.NewWebhookSourceRepository(database)
+	handler := NewWebhookHandler(repo.NewTodoRepository(database), sources, nil,
+		testReplayWindow, replay.NewMemoryCache(testReplayWindow), nil, events.NewBroadcaster(0))
+	engine := gin.New()
+	engine.POST("/webhooks/:source", handler.HandleSource)
+
+	source, err := sources.Create("payments", repo.WebhookSourceFields{
+		Name:         "payments",
+		Secret:       "source-secret",
+		ProjectLabel: "Payments",
+		Events:       "secrets.modified",
+	}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("create webhook source: %v", err)
+	}
+
+	post := func(slug, secret, body string) *httptest.ResponseRecorder {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/"+slug, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(signatureHeader, fmt.Sprintf("t=%d;sha256=%s", time.Now().UnixMilli(), hex.EncodeToString(mac.Sum(nil))))
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// 不存在的来源与签名错误一样返回 401
+	if w := post("unknown", source.Secret, webhookBody(time.Now())); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown source = %d, want 401", w.Code)
+	}
+	// 来源只接受自己的 Secret，默认地址的 Secret 不能通过验证
+	if w := post(source.Slug, testWebhookSecret, webhookBody(time.Now())); w.Code != http.StatusUnauthorized {
+		t.Fatalf("default secret on source = %d, want 401", w.Code)
+	}
+
+	// 载荷中没有项目名时使用来源的默认项目名，待办事项标记来源
+	w := post(source.Slug, source.Secret, webhookBody(time.Now()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("source delivery = %d %s, want 200", w.Code, w.Body)
+	}
+	var resp struct {
+		Data TodoResponse `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Data.ProjectName != "Payments" || resp.Data.Source != "payments" {
+		t.Fatalf("todo projectName = %q, source = %q, want Payments, payments", resp.Data.ProjectName, resp.Data.Source)
+	}
+
+	// 来源没有列出的事件被忽略
+	body := fmt.Sprintf(`{"event":"test","project":{"secretPath":"/db"},"timestamp":%d}`, time.Now().UnixMilli())
+	if w := post(source.Slug, source.Secret, body); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ignored") {
+		t.Fatalf("filtered event = %d %s, want 200 ignored", w.Code, w.Body)
+	}
+}
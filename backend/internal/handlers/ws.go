@@ -0,0 +1,395 @@
+// Package handlers 包含 WebSocket 实时通道的处理逻辑。
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/repo"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	// wsWriteTimeout 是单次写入的超时时间。
+	wsWriteTimeout = 10 * time.Second
+	// wsPongTimeout 是等待客户端响应 Pong 的最长时间，超时视为连接已断开。
+	wsPongTimeout = 60 * time.Second
+	// wsPingInterval 是服务端发送 Ping 的间隔，必须小于 wsPongTimeout。
+	wsPingInterval = 30 * time.Second
+	// wsMaxMessageSize 是客户端单条消息的最大字节数。
+	wsMaxMessageSize = 64 * 1024
+	// wsMaxSubscriptions 是单个连接允许的订阅数量上限。
+	wsMaxSubscriptions = 32
+	// wsSendBuffer 是待发送消息的缓冲区大小。
+	wsSendBuffer = 64
+)
+
+// WebSocket 消息类型。
+const (
+	// 客户端 → 服务端
+	wsTypeSubscribe   = "subscribe"
+	wsTypeUnsubscribe = "unsubscribe"
+	wsTypeComplete    = "complete"
+	wsTypeReopen      = "reopen"
+
+	// 服务端 → 客户端
+	wsTypeSubscribed   = "subscribed"
+	wsTypeUnsubscribed = "unsubscribed"
+	wsTypeResult       = "result"
+	wsTypeEvent        = "event"
+	wsTypeError        = "error"
+)
+
+// WSHandler 提供双向的 WebSocket 通道：
+// 客户端可以按项目、环境、路径前缀订阅待办事项变更，也可以通过同一连接标记完成或重新打开待办。
+type WSHandler struct {
+	repo        *repo.TodoRepository
+	broadcaster *events.Broadcaster
+	upgrader    websocket.Upgrader
+}
+
+// NewWSHandler 创建 WSHandler 实例。
+// allowOrigin 用于校验跨域连接的 Origin，通常与 CORS 共用同一套规则。
+func NewWSHandler(repo *repo.TodoRepository, broadcaster *events.Broadcaster, allowOrigin func(string) bool) *WSHandler {
+	return &WSHandler{
+		repo:        repo,
+		broadcaster: broadcaster,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			CheckOrigin: func(r *http.Request) bool {
+				return checkWSOrigin(r, allowOrigin)
+			},
+		},
+	}
+}
+
+// wsFilter 是订阅的过滤条件。
+// 不同字段之间是“且”的关系，同一字段内的多个取值是“或”的关系，空字段表示不限制。
+type wsFilter struct {
+	ProjectIDs   []string      `json:"projectIds"`
+	Environments []string      `json:"environments"`
+	PathPrefixes []string      `json:"pathPrefixes"`
+	Events       []events.Type `json:"events"`
+}
+
+// matches 判断事件是否满足过滤条件。
+func (f wsFilter) matches(event events.Event) bool {
+	if len(f.ProjectIDs) > 0 && !slices.Contains(f.ProjectIDs, event.Todo.ProjectID) {
+		return false
+	}
+	if len(f.Environments) > 0 && !slices.Contains(f.Environments, event.Todo.Environment) {
+		return false
+	}
+	if len(f.Events) > 0 && !slices.Contains(f.Events, event.Type) {
+		return false
+	}
+	if len(f.PathPrefixes) > 0 {
+		for _, prefix := range f.PathPrefixes {
+			if strings.HasPrefix(event.Todo.SecretPath, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// wsClientMessage 是客户端发送的消息。
+type wsClientMessage struct {
+	// Type 为 subscribe、unsubscribe、complete 或 reopen。
+	Type string `json:"type"`
+	// ID 由客户端生成，服务端在响应中原样带回。
+	// 对于 subscribe，ID 同时作为订阅标识，unsubscribe 时使用同一个 ID。
+	ID string `json:"id"`
+	// Filter 是订阅的过滤条件，仅 subscribe 使用。
+	Filter wsFilter `json:"filter"`
+	// TodoID 是要操作的待办事项，仅 complete / reopen 使用。
+	TodoID uint `json:"todoId"`
+	// Note 是完成备注，仅 complete 使用。
+	Note string `json:"note"`
+}
+
+// wsServerMessage 是服务端发送的消息。
+type wsServerMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	// Subscriptions 是事件命中的订阅 ID，仅 event 消息使用。
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	// EventID 与 SSE 的事件 ID 一致，仅 event 消息使用。
+	EventID uint64        `json:"eventId,omitempty"`
+	Event   *streamEvent  `json:"event,omitempty"`
+	Todo    *TodoResponse `json:"todo,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// wsConn 保存单个连接的状态。
+type wsConn struct {
+	conn *websocket.Conn
+	send chan wsServerMessage
+
+	mu            sync.Mutex
+	subscriptions map[string]wsFilter
+}
+
+// Serve 将请求升级为 WebSocket 连接。
+//
+//	@Summary		WebSocket 实时通道
+//	@Description	升级为 WebSocket 连接。客户端发送 JSON 消息：
+//	@Description	{"type":"subscribe","id":"s1","filter":{"projectIds":[],"environments":["prod"],"pathPrefixes":["/svc"],"events":[]}} 订阅变更；
+//	@Description	{"type":"unsubscribe","id":"s1"} 取消订阅；
+//	@Description	{"type":"complete","id":"r1","todoId":1,"note":"..."} / {"type":"reopen","id":"r2","todoId":1} 修改完成状态（需要 operator 角色）。
+//	@Description	服务端以 subscribed、unsubscribed、result、event、error 类型的消息响应
+//	@Tags			todos
+//	@Security		BearerAuth
+//	@Success		101	{string}	string				"切换协议"
+//	@Failure		401	{object}	map[string]string	"未认证"
+//	@Failure		403	{object}	map[string]string	"权限不足"
+//	@Router			/ws [get]
+func (h *WSHandler) Serve(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade 失败时已经写入了错误响应
+		slog.WarnContext(c.Request.Context(), "WebSocket 升级失败", "client_ip", c.ClientIP(), "error", err)
+		return
+	}
+
+	client := &wsConn{
+		conn:          conn,
+		send:          make(chan wsServerMessage, wsSendBuffer),
+		subscriptions: make(map[string]wsFilter),
+	}
+	sub, _, _ := h.broadcaster.Subscribe(0)
+
+	// 写循环在独立的 goroutine 中运行，它是唯一写入连接的地方。
+	// 写循环提前退出（写入失败或订阅被断开）时关闭连接，让读循环随之结束。
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.writeLoop(sub, h.broadcaster)
+		conn.Close()
+	}()
+
+	h.readLoop(c, client)
+
+	// 读循环结束（客户端断开或出错）时会关闭发送队列，写循环随之退出，之后再取消订阅
+	<-done
+	sub.Close()
+}
+
+// readLoop 读取并处理客户端消息，直到连接断开。
+func (h *WSHandler) readLoop(c *gin.Context, client *wsConn) {
+	defer close(client.send)
+
+	client.conn.SetReadLimit(wsMaxMessageSize)
+	_ = client.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		// 读取失败说明连接已断开（或超时未响应 Ping），结束读循环
+		_, data, err := client.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// 消息格式错误时连接仍然可用，返回错误后继续读取
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			client.reply(wsServerMessage{Type: wsTypeError, Error: "invalid message"})
+			continue
+		}
+
+		client.reply(h.handleMessage(c, client, msg))
+	}
+}
+
+// handleMessage 处理一条客户端消息，返回需要发送给客户端的响应。
+func (h *WSHandler) handleMessage(c *gin.Context, client *wsConn, msg wsClientMessage) wsServerMessage {
+	fail := func(message string) wsServerMessage {
+		return wsServerMessage{Type: wsTypeError, ID: msg.ID, Error: message}
+	}
+
+	switch msg.Type {
+	case wsTypeSubscribe:
+		if msg.ID == "" {
+			return fail("id is required")
+		}
+		client.mu.Lock()
+		_, exists := client.subscriptions[msg.ID]
+		if !exists && len(client.subscriptions) >= wsMaxSubscriptions {
+			client.mu.Unlock()
+			return fail("too many subscriptions")
+		}
+		// 重复订阅同一个 ID 时覆盖原有过滤条件
+		client.subscriptions[msg.ID] = msg.Filter
+		client.mu.Unlock()
+		return wsServerMessage{Type: wsTypeSubscribed, ID: msg.ID}
+
+	case wsTypeUnsubscribe:
+		client.mu.Lock()
+		_, exists := client.subscriptions[msg.ID]
+		delete(client.subscriptions, msg.ID)
+		client.mu.Unlock()
+		if !exists {
+			return fail("subscription not found")
+		}
+		return wsServerMessage{Type: wsTypeUnsubscribed, ID: msg.ID}
+
+	case wsTypeComplete, wsTypeReopen:
+		// 与 HTTP 接口一致，修改完成状态需要 operator 角色；
+		// 关闭认证时没有身份信息，视为拥有全部权限
+		if principal, ok := auth.PrincipalFrom(c); ok && !principal.Role.Allows(auth.RoleOperator) {
+			return fail("forbidden: requires " + string(auth.RoleOperator) + " role")
+		}
+		if msg.TodoID == 0 {
+			return fail("todoId is required")
+		}
+
+		var (
+			item models.TodoItem
+			err  error
+		)
+		if msg.Type == wsTypeComplete {
+			note := strings.TrimSpace(msg.Note)
+			if utf8.RuneCountInString(note) > maxCompletionNoteLength {
+				return fail("note is too long")
+			}
+			item, err = h.repo.WithContext(c.Request.Context()).Complete(msg.TodoID, time.Now().UTC(), actorName(c), note)
+		} else {
+			item, err = h.repo.WithContext(c.Request.Context()).Reopen(msg.TodoID, time.Now().UTC())
+		}
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fail("todo not found")
+			}
+			return fail("update todo failed")
+		}
+
+		eventType := events.TodoReopened
+		if item.IsCompleted {
+			eventType = events.TodoCompleted
+		}
+		h.broadcaster.Publish(eventType, item)
+
+		response := toTodoResponse(item)
+		return wsServerMessage{Type: wsTypeResult, ID: msg.ID, Todo: &response}
+
+	default:
+		return fail("unknown message type")
+	}
+}
+
+// reply 将响应放入发送队列。
+// 队列已满说明客户端没有在读取，丢弃响应，由写入超时断开连接。
+func (client *wsConn) reply(msg wsServerMessage) {
+	select {
+	case client.send <- msg:
+	default:
+	}
+}
+
+// writeLoop 负责所有写操作：响应消息、匹配订阅的事件与定时 Ping。
+// broadcaster 用于判断订阅被断开的原因：服务关闭还是消费过慢。
+func (client *wsConn) writeLoop(sub *events.Subscription, broadcaster *events.Broadcaster) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case msg, ok := <-client.send:
+			if !ok {
+				// 读循环已经结束，礼貌地关闭连接
+				client.writeClose(websocket.CloseNormalClosure, "")
+				return
+			}
+			if err := client.writeJSON(msg); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if broadcaster.Closed() {
+					// 服务正在关闭，通知客户端稍后重连
+					client.writeClose(websocket.CloseServiceRestart, "server shutting down")
+					return
+				}
+				// 消费过慢被广播器断开，通知客户端重连
+				client.writeClose(websocket.CloseTryAgainLater, "subscriber too slow")
+				return
+			}
+			matched := client.match(event)
+			if len(matched) == 0 {
+				continue
+			}
+			payload := streamEvent{Type: event.Type, Todo: toTodoResponse(event.Todo), At: event.At.Format(timeLayout)}
+			if err := client.writeJSON(wsServerMessage{
+				Type:          wsTypeEvent,
+				Subscriptions: matched,
+				EventID:       event.ID,
+				Event:         &payload,
+			}); err != nil {
+				return
+			}
+		case <-ping.C:
+			_ = client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// match 返回事件命中的订阅 ID。
+func (client *wsConn) match(event events.Event) []string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	var matched []string
+	for id, filter := range client.subscriptions {
+		if filter.matches(event) {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+// writeJSON 带超时地写入一条 JSON 消息。
+func (client *wsConn) writeJSON(msg wsServerMessage) error {
+	_ = client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return client.conn.WriteJSON(msg)
+}
+
+// writeClose 发送关闭帧。
+func (client *wsConn) writeClose(code int, reason string) {
+	_ = client.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteTimeout))
+}
+
+// checkWSOrigin 校验 WebSocket 握手的 Origin 头。
+// 非浏览器客户端通常不发送 Origin，直接放行；同源请求放行；
+// 其余情况交给 allowOrigin 判断，防止第三方页面借用浏览器 Cookie 建立连接。
+func checkWSOrigin(r *http.Request, allowOrigin func(string) bool) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	return allowOrigin != nil && allowOrigin(origin)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/events"
+	"backend/internal/models"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// testRoleHeader 指定测试连接的角色，为空时不写入身份（相当于关闭认证）。
+const testRoleHeader = "X-Test-Role"
+
+// newWSTestServer 启动一个只注册了 /ws 的服务，允许来自 https://app.example.com 的跨域连接。
+func newWSTestServer(t *testing.T) (*httptest.Server, *repo.TodoRepository, *events.Broadcaster) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	todos := repo.NewTodoRepository(testdb.SQLite(t))
+	broadcaster := events.NewBroadcaster(0)
+	handler := NewWSHandler(todos, broadcaster, func(origin string) bool { return origin == "https://app.example.com" })
+
+	engine := gin.New()
+	engine.GET("/ws", func(c *gin.Context) {
+		if role := c.GetHeader(testRoleHeader); role != "" {
+			auth.SetPrincipal(c, auth.Principal{Subject: "test", Name: "test", Role: auth.Role(role)})
+		}
+		c.Next()
+	}, handler.Serve)
+
+	server := httptest.NewServer(engine)
+	// 先关闭广播器断开所有连接，再关闭服务
+	t.Cleanup(server.Close)
+	t.Cleanup(broadcaster.Close)
+	return server, todos, broadcaster
+}
+
+// dialWS 建立 WebSocket 连接。
+func dialWS(t *testing.T, server *httptest.Server, header http.Header) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("Dial: %v (status %d)", err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// roundTrip 发送一条消息并读取下一条服务端消息。
+func roundTrip(t *testing.T, conn *websocket.Conn, msg wsClientMessage) wsServerMessage {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	return readWS(t, conn)
+}
+
+// readWS 读取下一条服务端消息。
+func readWS(t *testing.T, conn *websocket.Conn) wsServerMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply wsServerMessage
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	return reply
+}
+
+func TestWSSubscriptionFilters(t *testing.T) {
+	server, _, broadcaster := newWSTestServer(t)
+	conn := dialWS(t, server, nil)
+
+	filters := map[string]wsFilter{
+		"project": {ProjectIDs: []string{"p1"}},
+		"env":     {Environments: []string{"prod"}},
+		"path":    {PathPrefixes: []string{"/svc/"}},
+		"reset":   {ProjectIDs: []string{"p2"}, Events: []events.Type{events.TodoReset}},
+	}
+	for id, filter := range filters {
+		if reply := roundTrip(t, conn, wsClientMessage{Type: wsTypeSubscribe, ID: id, Filter: filter}); reply.Type != wsTypeSubscribed || reply.ID != id {
+			t.Fatalf("subscribe %s = %+v", id, reply)
+		}
+	}
+
+	// publish 发布一个事件；want 为空表示这个事件不命中任何订阅，不应该发送给客户端。
+	// 不命中的事件被丢弃后，下一条收到的消息就是之后发布的、命中订阅的事件
+	steps := []struct {
+		eventType events.Type
+		todo      models.TodoItem
+		want      []string
+	}{
+		{eventType: events.TodoCreated, todo: models.TodoItem{ProjectID: "p1", Environment: "dev", SecretPath: "/db"}, want: []string{"project"}},
+		{eventType: events.TodoCreated, todo: models.TodoItem{ProjectID: "p9", Environment: "dev", SecretPath: "/db"}},
+		{eventType: events.TodoCreated, todo: models.TodoItem{ProjectID: "p1", Environment: "prod", SecretPath: "/svc/api"}, want: []string{"env", "path", "project"}},
+		{eventType: events.TodoCreated, todo: models.TodoItem{ProjectID: "p2", Environment: "dev", SecretPath: "/svcs"}},
+		{eventType: events.TodoReset, todo: models.TodoItem{ProjectID: "p2", Environment: "dev", SecretPath: "/db"}, want: []string{"reset"}},
+	}
+	for i, step := range steps {
+		event := broadcaster.Publish(step.eventType, step.todo)
+		if len(step.want) == 0 {
+			continue
+		}
+		reply := readWS(t, conn)
+		slices.Sort(reply.Subscriptions)
+		if reply.Type != wsTypeEvent || reply.EventID != event.ID || !slices.Equal(reply.Subscriptions, step.want) {
+			t.Fatalf("step %d: message = %+v, want event %d for %v", i, reply, event.ID, step.want)
+		}
+	}
+
+	// 取消订阅后不再收到只命中它的事件
+	if reply := roundTrip(t, conn, wsClientMessage{Type: wsTypeUnsubscribe, ID: "project"}); reply.Type != wsTypeUnsubscribed {
+		t.Fatalf("unsubscribe = %+v", reply)
+	}
+	if reply := roundTrip(t, conn, wsClientMessage{Type: wsTypeUnsubscribe, ID: "project"}); reply.Type != wsTypeError {
+		t.Fatalf("second unsubscribe = %+v, want error", reply)
+	}
+	broadcaster.Publish(events.TodoCreated, models.TodoItem{ProjectID: "p1", Environment: "dev", SecretPath: "/db"})
+	marker := broadcaster.Publish(events.TodoCreated, models.TodoItem{Environment: "prod"})
+	if reply := readWS(t, conn); reply.EventID != marker.ID || !slices.Equal(reply.Subscriptions, []string{"env"}) {
+		t.Fatalf("message after unsubscribe = %+v, want only event %d", reply, marker.ID)
+	}
+}
+
+func TestWSCompleteRequiresOperator(t *testing.T) {
+	server, todos, _ := newWSTestServer(t)
+	item, err := todos.Create(repo.TodoFields{SecretPath: "/db"}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	header := func(role auth.Role) http.Header {
+		return http.Header{testRoleHeader: []string{string(role)}}
+	}
+
+	// viewer 可以订阅，但不能修改完成状态
+	viewer := dialWS(t, server, header(auth.RoleViewer))
+	for _, msgType := range []string{wsTypeComplete, wsTypeReopen} {
+		reply := roundTrip(t, viewer, wsClientMessage{Type: msgType, ID: "r1", TodoID: item.ID})
+		if reply.Type != wsTypeError || !strings.HasPrefix(reply.Error, "forbidden") {
+			t.Fatalf("viewer %s = %+v, want forbidden", msgType, reply)
+		}
+	}
+	if current, err := todos.GetByID(item.ID); err != nil || current.IsCompleted {
+		t.Fatalf("todo after viewer complete = %+v, %v; want unchanged", current, err)
+	}
+
+	operator := dialWS(t, server, header(auth.RoleOperator))
+	reply := roundTrip(t, operator, wsClientMessage{Type: wsTypeComplete, ID: "r2", TodoID: item.ID, Note: "rotated"})
+	if reply.Type != wsTypeResult || reply.ID != "r2" || reply.Todo == nil || !reply.Todo.IsCompleted {
+		t.Fatalf("operator complete = %+v, want a completed todo", reply)
+	}
+	reply = roundTrip(t, operator, wsClientMessage{Type: wsTypeReopen, ID: "r3", TodoID: item.ID})
+	if reply.Type != wsTypeResult || reply.Todo == nil || reply.Todo.IsCompleted {
+		t.Fatalf("operator reopen = %+v, want an open todo", reply)
+	}
+	if reply := roundTrip(t, operator, wsClientMessage{Type: wsTypeComplete, ID: "r4", TodoID: 999}); reply.Type != wsTypeError || reply.Error != "todo not found" {
+		t.Fatalf("complete missing todo = %+v, want todo not found", reply)
+	}
+}
+
+func TestWSOriginCheck(t *testing.T) {
+	server, _, _ := newWSTestServer(t)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	cases := []struct {
+		origin string
+		ok     bool
+	}{
+		{origin: "", ok: true},
+		{origin: server.URL, ok: true},
+		{origin: "https://app.example.com", ok: true},
+		{origin: "https://evil.example.com", ok: false},
+	}
+	for _, tc := range cases {
+		header := http.Header{}
+		if tc.origin != "" {
+			header.Set("Origin", tc.origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		if tc.ok {
+			if err != nil {
+				t.Errorf("origin %q: Dial = %v, want success", tc.origin, err)
+				continue
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Errorf("origin %q: Dial succeeded, want rejection", tc.origin)
+			continue
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("origin %q: Dial = %v, want 403", tc.origin, err)
+		}
+	}
+}
+
+func TestWSClosesOnShutdown(t *testing.T) {
+	server, _, broadcaster := newWSTestServer(t)
+	conn := dialWS(t, server, nil)
+	if reply := roundTrip(t, conn, wsClientMessage{Type: wsTypeSubscribe, ID: "all"}); reply.Type != wsTypeSubscribed {
+		t.Fatalf("subscribe reply = %+v, want subscribed", reply)
+	}
+
+	// 服务关闭时以 1012 (Service Restart) 关闭连接，客户端据此重连
+	broadcaster.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Fatalf("ReadMessage = %v, want close %d", err, websocket.CloseServiceRestart)
+	}
+}
@@ -0,0 +1,124 @@
+// Package logging 统一配置 slog 日志输出。
+//
+// 所有日志都通过 slog 输出，格式（json / text）与级别可配置。
+// 请求处理过程中使用 slog.InfoContext 等带 Context 的函数记录日志时，
+// 会自动附带请求 ID（启用链路追踪时还有 Trace ID），方便在日志系统中追踪同一个请求的所有日志。
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 日志输出格式。
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// requestIDKey 是请求 ID 在 context.Context 中的键。
+// 使用未导出的类型，避免与其他包的键冲突。
+type requestIDKey struct{}
+
+// WithRequestID 返回携带请求 ID 的 Context。
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 返回 Context 中的请求 ID，没有时返回空字符串。
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ParseLevel 解析日志级别：debug、info、warn、error，空字符串为 info。
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", value)
+	}
+}
+
+// ParseFormat 解析日志格式：text 或 json，空字符串为 text。
+func ParseFormat(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q", value)
+	}
+}
+
+// Setup 按格式与级别创建 slog Logger 并设为默认 Logger。
+// 标准库 log 包的输出也会转到这个 Logger，避免出现两种格式混杂的日志。
+func Setup(w io.Writer, format string, level slog.Level) *slog.Logger {
+	currentLevel.Set(level)
+	options := &slog.HandlerOptions{Level: currentLevel}
+
+	var handler slog.Handler
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(w, options)
+	} else {
+		handler = slog.NewTextHandler(w, options)
+	}
+
+	logger := slog.New(contextHandler{handler})
+	slog.SetDefault(logger)
+	// SetDefault 之后 log.Printf 会以 INFO 级别写入 slog，去掉 log 包自带的时间前缀
+	log.SetFlags(0)
+	return logger
+}
+
+// currentLevel 是默认 Logger 的日志级别，可以通过 SetLevel 在运行时修改。
+var currentLevel = new(slog.LevelVar)
+
+// SetLevel 修改日志级别，立即对所有日志生效，用于重新加载配置时不重启服务调整级别。
+func SetLevel(level slog.Level) {
+	currentLevel.Set(level)
+}
+
+// contextHandler 在每条日志中附加 Context 里的请求 ID 与链路追踪的 Trace ID。
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle 实现 slog.Handler 接口。
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	// 只在 Span 被采样时记录，未采样的 Trace ID 在追踪后端中查不到
+	if span := trace.SpanContextFromContext(ctx); span.IsSampled() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs 实现 slog.Handler 接口。
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup 实现 slog.Handler 接口。
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
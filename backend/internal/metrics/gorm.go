@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// gormStartKey 是当前语句开始执行时间在 gorm.DB 实例中的存储键。
+const gormStartKey = "metrics:start"
+
+var (
+	// dbQueryDuration 按操作与表统计 SQL 语句耗时，包括在连接池中排队等待连接的时间。
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQL 语句耗时（秒），按操作与表区分。",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// dbQueryErrors 按操作与表统计执行失败的 SQL 语句。
+	dbQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "执行失败的 SQL 语句数量，按操作与表区分。",
+	}, []string{"operation", "table"})
+)
+
+// gormPlugin 记录每条 SQL 语句的耗时与错误。
+type gormPlugin struct{}
+
+// GORMPlugin 返回记录 SQL 语句耗时与错误的 GORM 插件。
+// 与 tracing.GORMPlugin 不同，没有父 Span 的语句（例如发件箱的后台轮询）同样会被记录。
+// 标签只使用操作类型与表名，原生 SQL（Exec/Raw）的表名为空。
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+// Name 实现 gorm.Plugin 接口。
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在每类操作的执行前后注册回调。
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", startGORMTimer),
+		cb.Create().After("gorm:create").Register("metrics:after_create", observeGORM("INSERT")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", startGORMTimer),
+		cb.Query().After("gorm:query").Register("metrics:after_query", observeGORM("SELECT")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", startGORMTimer),
+		cb.Update().After("gorm:update").Register("metrics:after_update", observeGORM("UPDATE")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", startGORMTimer),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", observeGORM("DELETE")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", startGORMTimer),
+		cb.Row().After("gorm:row").Register("metrics:after_row", observeGORM("ROW")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", startGORMTimer),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", observeGORM("RAW")),
+	)
+}
+
+// startGORMTimer 在语句执行前记录开始时间。
+func startGORMTimer(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+// observeGORM 返回在语句执行后记录耗时与错误的回调。
+// 记录不存在（gorm.ErrRecordNotFound）是正常的查询结果，不计为错误。
+func observeGORM(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(gormStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		dbQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			dbQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
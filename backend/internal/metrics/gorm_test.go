@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"backend/internal/testdb"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+)
+
+type metricsTestRow struct {
+	ID   uint
+	Name string
+}
+
+func TestGORMPlugin(t *testing.T) {
+	database := testdb.SQLite(t)
+	if err := database.Use(GORMPlugin()); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := database.AutoMigrate(&metricsTestRow{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	const table = "metrics_test_rows"
+	insertsBefore := testutil.CollectAndCount(dbQueryDuration)
+	queryErrorsBefore := testutil.ToFloat64(dbQueryErrors.WithLabelValues("SELECT", table))
+
+	if err := database.Create(&metricsTestRow{Name: "a"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := testutil.CollectAndCount(dbQueryDuration); got <= insertsBefore {
+		t.Fatalf("histogram series = %d, want a new INSERT series", got)
+	}
+
+	// 记录不存在是正常结果，不计为错误
+	var row metricsTestRow
+	if err := database.First(&row, 42).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("First = %v, want gorm.ErrRecordNotFound", err)
+	}
+	if got := testutil.ToFloat64(dbQueryErrors.WithLabelValues("SELECT", table)); got != queryErrorsBefore {
+		t.Fatalf("SELECT errors = %v, want %v", got, queryErrorsBefore)
+	}
+
+	// 真正的数据库错误按操作与表计数
+	if err := database.Where("missing_column = ?", 1).Find(&[]metricsTestRow{}).Error; err == nil {
+		t.Fatal("query on a missing column succeeded")
+	}
+	if got := testutil.ToFloat64(dbQueryErrors.WithLabelValues("SELECT", table)); got != queryErrorsBefore+1 {
+		t.Fatalf("SELECT errors = %v, want %v", got, queryErrorsBefore+1)
+	}
+}
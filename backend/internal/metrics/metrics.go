@@ -0,0 +1,170 @@
+// Package metrics 定义服务暴露给 Prometheus 的监控指标。
+// Webhook 投递结果与 HTTP 请求耗时由 Handler 与中间件在处理请求时记录，SQL 语句耗时由 GORM 插件记录，
+// 待办事项数量等状态类指标则在每次抓取 /metrics 时从数据库实时查询。
+package metrics
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "infisical_notification"
+
+// Webhook 投递结果，对应 webhook_deliveries_total 的 result 标签。
+const (
+	WebhookResultOK             = "ok"
+	WebhookResultIgnored        = "ignored"
+	WebhookResultUnauthorized   = "unauthorized"
+	WebhookResultInvalidPayload = "invalid_payload"
+	WebhookResultDBError        = "db_error"
+)
+
+// Webhook 被拒绝的原因，对应 webhook_deliveries_total 的 reason 标签，仅在 result 为 unauthorized 时使用。
+const (
+	ReasonUnknownSource    = "unknown_source"
+	ReasonMissingSecret    = "missing_secret"
+	ReasonMissingSignature = "missing_signature"
+	ReasonInvalidSignature = "invalid_signature"
+	ReasonSecretsExpired   = "secrets_expired"
+	ReasonReplayed         = "replayed"
+	ReasonStaleTimestamp   = "stale_timestamp"
+)
+
+// event 标签的特殊取值。
+const (
+	// EventUnknown 用于签名验证通过之前（尚未解析载荷）就结束的投递。
+	EventUnknown = "unknown"
+
+	// EventOther 用于不支持的事件类型。
+	EventOther = "other"
+)
+
+var (
+	// webhookDeliveries 按事件类型与处理结果统计 Webhook 投递次数。
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook 投递次数，按事件类型、处理结果与拒绝原因区分。",
+	}, []string{"event", "result", "reason"})
+
+	// httpRequestDuration 按路由统计 HTTP 请求耗时。
+	// route 使用注册时的路由模板（例如 /api/todos/:id），避免把 ID 写进标签导致序列数量无限增长。
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求耗时（秒），按方法、路由与状态码区分。",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ObserveWebhook 记录一次 Webhook 投递的处理结果。
+// event 只应传入支持的事件类型、EventUnknown 或 EventOther，防止任意的事件名产生大量时间序列。
+func ObserveWebhook(event, result, reason string) {
+	webhookDeliveries.WithLabelValues(event, result, reason).Inc()
+}
+
+// ObserveHTTPRequest 记录一次 HTTP 请求的耗时。
+// 没有匹配到任何路由的请求（404）route 为空，统一记为 unmatched。
+func ObserveHTTPRequest(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// TodoStats 是某一时刻待办事项的汇总状态。
+type TodoStats struct {
+	Open      int64
+	Completed int64
+
+	// OldestOpenAt 是等待最久的未完成待办事项变为未完成的时间，没有未完成的待办事项时为 nil。
+	OldestOpenAt *time.Time
+}
+
+// TodoStatsSource 提供待办事项的汇总状态，由 repo.TodoRepository 实现。
+type TodoStatsSource interface {
+	Stats() (TodoStats, error)
+}
+
+// todoCollector 在每次抓取时查询待办事项的汇总状态。
+// 相比在每次写入时维护计数，这样即使数据被其他实例或手动 SQL 修改，指标也始终与数据库一致。
+type todoCollector struct {
+	source TodoStatsSource
+
+	todos          *prometheus.Desc
+	oldestOpenAge  *prometheus.Desc
+	scrapeFailures prometheus.Counter
+}
+
+func newTodoCollector(source TodoStatsSource) *todoCollector {
+	return &todoCollector{
+		source: source,
+		todos: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "todos"),
+			"待办事项数量，按状态（open、completed）区分。",
+			[]string{"state"}, nil,
+		),
+		oldestOpenAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "oldest_open_todo_age_seconds"),
+			"等待最久的未完成待办事项自变为未完成以来的秒数，没有未完成的待办事项时为 0。",
+			nil, nil,
+		),
+		scrapeFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "todo_stats_errors_total",
+			Help:      "抓取指标时查询待办事项状态失败的次数。",
+		}),
+	}
+}
+
+// Describe 实现 prometheus.Collector 接口。
+func (c *todoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.todos
+	ch <- c.oldestOpenAge
+	c.scrapeFailures.Describe(ch)
+}
+
+// Collect 实现 prometheus.Collector 接口。
+// 查询失败时不输出待办事项指标（而不是输出 0），避免告警规则把数据库故障误判为“没有待办”。
+func (c *todoCollector) Collect(ch chan<- prometheus.Metric) {
+	defer c.scrapeFailures.Collect(ch)
+
+	stats, err := c.source.Stats()
+	if err != nil {
+		slog.Error("查询待办事项指标失败", "error", err)
+		c.scrapeFailures.Inc()
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.todos, prometheus.GaugeValue, float64(stats.Open), "open")
+	ch <- prometheus.MustNewConstMetric(c.todos, prometheus.GaugeValue, float64(stats.Completed), "completed")
+
+	age := 0.0
+	if stats.OldestOpenAt != nil {
+		age = max(time.Since(*stats.OldestOpenAt).Seconds(), 0)
+	}
+	ch <- prometheus.MustNewConstMetric(c.oldestOpenAge, prometheus.GaugeValue, age)
+}
+
+// NewHandler 创建 /metrics 的 HTTP 处理器。
+// 使用独立的 Registry 而不是 prometheus 的全局默认 Registry，只暴露这里注册的指标，
+// 另外附带 Go 运行时与进程指标（内存、goroutine、文件描述符等）。
+func NewHandler(todos TodoStatsSource) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		webhookDeliveries,
+		httpRequestDuration,
+		dbQueryDuration,
+		dbQueryErrors,
+		newTodoCollector(todos),
+	)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeStats 返回固定的汇总状态或错误。
+type fakeStats struct {
+	stats TodoStats
+	err   error
+}
+
+func (f fakeStats) Stats() (TodoStats, error) { return f.stats, f.err }
+
+func TestTodoCollector(t *testing.T) {
+	oldest := time.Now().Add(-time.Hour)
+	collector := newTodoCollector(fakeStats{stats: TodoStats{Open: 2, Completed: 3, OldestOpenAt: &oldest}})
+
+	want := `
+# HELP infisical_notification_todos 待办事项数量，按状态（open、completed）区分。
+# TYPE infisical_notification_todos gauge
+infisical_notification_todos{state="completed"} 3
+infisical_notification_todos{state="open"} 2
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want), "infisical_notification_todos"); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.CollectAndCount(collector, "infisical_notification_oldest_open_todo_age_seconds"); got != 1 {
+		t.Fatalf("oldest_open_todo_age_seconds series = %d, want 1", got)
+	}
+
+	// 查询失败时不输出待办事项指标，只增加失败计数
+	failing := newTodoCollector(fakeStats{err: errors.New("database is locked")})
+	if got := testutil.CollectAndCount(failing, "infisical_notification_todos", "infisical_notification_oldest_open_todo_age_seconds"); got != 0 {
+		t.Fatalf("todo series on failure = %d, want 0", got)
+	}
+	if got := testutil.ToFloat64(failing.scrapeFailures); got != 1 {
+		t.Fatalf("todo_stats_errors_total = %v, want 1", got)
+	}
+}
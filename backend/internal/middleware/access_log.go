@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog 返回一个使用 slog 记录访问日志的中间件，取代 Gin 自带的文本日志。
+// skipPaths 中的路径（例如健康检查）不记录。
+func AccessLog(skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.Request.URL.Path
+		if skip[path] {
+			return
+		}
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// 只记录路径，不记录查询参数，避免把 Token 等敏感信息写进日志
+		slog.Log(c.Request.Context(), level, "HTTP 请求",
+			"method", c.Request.Method,
+			"path", path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		)
+	}
+}
+
+// Recovery 返回一个捕获 panic 的中间件，用 slog 记录错误与调用栈并返回 500。
+func Recovery() gin.HandlerFunc {
+	// writer 传 nil，不让 Gin 再输出一份文本格式的日志
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		slog.ErrorContext(c.Request.Context(), "请求处理发生 panic", "error", err, "stack", string(debug.Stack()))
+		handlers.RespondError(c, http.StatusInternalServerError, "internal server error")
+		c.Abort()
+	})
+}
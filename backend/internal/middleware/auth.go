@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/handlers"
+	"backend/internal/repo"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// bootstrapSubject 是通过 TODO_ADMIN_TOKEN 认证时的身份标识，拥有 admin 角色。
+const bootstrapSubject = "bootstrap"
+
+// Auth 返回一个认证中间件，支持两种方式：
+//   - Bearer API Token，请求头格式：Authorization: Bearer <token>
+//   - OIDC 登录后签发的会话 Cookie（sessions 为 nil 表示未启用 OIDC）
+//
+// 请求携带 Authorization 头时只按 Token 校验，不再回退到 Cookie。
+// bootstrapToken 来自 TODO_ADMIN_TOKEN，用于在没有任何 Token 时完成首次配置，为空表示不启用。
+// 认证成功后会将 auth.Principal 写入上下文，失败统一返回 401。
+func Auth(tokens *repo.TokenRepository, bootstrapToken string, sessions *auth.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" && sessions != nil {
+			authenticateSession(c, sessions)
+			return
+		}
+
+		token, ok := bearerToken(header)
+		if !ok {
+			handlers.RespondUnauthorized(c, "missing bearer token")
+			c.Abort()
+			return
+		}
+
+		// 使用常量时间比较，防止时序攻击
+		if bootstrapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bootstrapToken)) == 1 {
+			auth.SetPrincipal(c, auth.Principal{
+				Subject: bootstrapSubject,
+				Name:    bootstrapSubject,
+				Method:  auth.MethodToken,
+				Role:    auth.RoleAdmin,
+			})
+			c.Next()
+			return
+		}
+
+		item, err := tokens.Authenticate(auth.HashToken(token), time.Now().UTC())
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				handlers.RespondUnauthorized(c, "unknown api token")
+			} else {
+				handlers.RespondUnauthorized(c, "api token lookup failed: "+err.Error())
+			}
+			c.Abort()
+			return
+		}
+
+		role, err := auth.ParseRole(item.Role)
+		if err != nil {
+			handlers.RespondUnauthorized(c, "api token has invalid role: "+item.Role)
+			c.Abort()
+			return
+		}
+
+		auth.SetPrincipal(c, auth.Principal{
+			Subject: fmt.Sprintf("token:%d", item.ID),
+			Name:    item.Name,
+			Method:  auth.MethodToken,
+			Role:    role,
+		})
+		c.Next()
+	}
+}
+
+// authenticateSession 校验会话 Cookie。
+func authenticateSession(c *gin.Context, sessions *auth.Signer) {
+	value, err := c.Cookie(auth.SessionCookieName)
+	if err != nil || value == "" {
+		handlers.RespondUnauthorized(c, "missing bearer token or session cookie")
+		c.Abort()
+		return
+	}
+
+	session, err := sessions.DecodeSession(value, time.Now())
+	if err != nil {
+		handlers.RespondUnauthorized(c, "invalid or expired session")
+		c.Abort()
+		return
+	}
+
+	auth.SetPrincipal(c, auth.Principal{
+		Subject: session.Subject,
+		Name:    session.Name,
+		Method:  auth.MethodSession,
+		Role:    session.Role,
+	})
+	c.Next()
+}
+
+// bearerToken 从 Authorization 头中提取 Bearer Token。
+// scheme 部分不区分大小写。
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testBootstrapToken = "bootstrap-secret"
+
+// newAuthTestRouter 返回一个挂载了认证中间件的路由，/probe 返回认证得到的角色。
+func newAuthTestRouter(tokens *repo.TokenRepository, sessions *auth.Signer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/probe", Auth(tokens, testBootstrapToken, sessions), func(c *gin.Context) {
+		principal, _ := auth.PrincipalFrom(c)
+		c.String(http.StatusOK, string(principal.Role))
+	})
+	return engine
+}
+
+// probe 携带给定的请求头访问 /probe，返回状态码与响应体。
+func probe(engine *gin.Engine, header, cookie string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: cookie})
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestAuthBearerToken(t *testing.T) {
+	tokens := repo.NewTokenRepository(testdb.SQLite(t))
+	engine := newAuthTestRouter(tokens, nil)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	item, err := tokens.Create("ci", string(auth.RoleOperator), auth.HashToken(token), auth.DisplayPrefix(token), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		header string
+		status int
+		role   string
+	}{
+		{name: "missing", header: "", status: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic " + token, status: http.StatusUnauthorized},
+		{name: "empty token", header: "Bearer ", status: http.StatusUnauthorized},
+		{name: "unknown token", header: "Bearer itn_unknown", status: http.StatusUnauthorized},
+		// 数据库中只有哈希，直接提交哈希值不能通过认证
+		{name: "hash as token", header: "Bearer " + auth.HashToken(token), status: http.StatusUnauthorized},
+		{name: "api token", header: "Bearer " + token, status: http.StatusOK, role: string(auth.RoleOperator)},
+		{name: "case-insensitive scheme", header: "bearer " + token, status: http.StatusOK, role: string(auth.RoleOperator)},
+		{name: "bootstrap token", header: "Bearer " + testBootstrapToken, status: http.StatusOK, role: string(auth.RoleAdmin)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := probe(engine, tc.header, "")
+			if status != tc.status || (status == http.StatusOK && body != tc.role) {
+				t.Fatalf("status = %d body %q, want %d %q", status, body, tc.status, tc.role)
+			}
+		})
+	}
+
+	// 吊销之后同一个 Token 立即失效
+	if err := tokens.Delete(item.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if status, _ := probe(engine, "Bearer "+token, ""); status != http.StatusUnauthorized {
+		t.Fatalf("revoked token status = %d, want 401", status)
+	}
+}
+
+func TestAuthSession(t *testing.T) {
+	tokens := repo.NewTokenRepository(testdb.SQLite(t))
+	sessions := auth.NewSigner("session-secret")
+	engine := newAuthTestRouter(tokens, sessions)
+
+	encode := func(signer *auth.Signer, expiresAt time.Time) string {
+		t.Helper()
+		value, err := signer.EncodeSession(auth.Session{Subject: "oidc:alice", Name: "alice", Role: auth.RoleOperator, ExpiresAt: expiresAt.Unix()})
+		if err != nil {
+			t.Fatalf("EncodeSession: %v", err)
+		}
+		return value
+	}
+	valid := encode(sessions, time.Now().Add(time.Hour))
+
+	cases := []struct {
+		name   string
+		header string
+		cookie string
+		status int
+	}{
+		{name: "no credentials", status: http.StatusUnauthorized},
+		{name: "session", cookie: valid, status: http.StatusOK},
+		{name: "expired session", cookie: encode(sessions, time.Now().Add(-time.Minute)), status: http.StatusUnauthorized},
+		{name: "forged session", cookie: encode(auth.NewSigner("other-secret"), time.Now().Add(time.Hour)), status: http.StatusUnauthorized},
+		// 携带 Authorization 头时只按 Token 校验，不回退到 Cookie
+		{name: "bad token with session", header: "Bearer itn_unknown", cookie: valid, status: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := probe(engine, tc.header, tc.cookie)
+			if status != tc.status || (status == http.StatusOK && body != string(auth.RoleOperator)) {
+				t.Fatalf("status = %d body %q, want %d", status, body, tc.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireClientCert 返回一个要求 mTLS 客户端证书的中间件，失败统一返回 401。
+// 证书本身已在 TLS 握手时由 ClientCAs 校验（见 certs.ServerConfig），
+// 这里只检查连接是否出示了通过校验的证书：握手阶段还不知道请求的路由，
+// 所以是否必须出示证书只能在路由层按路由决定。
+func RequireClientCert() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := c.Request.TLS
+		if state == nil || len(state.VerifiedChains) == 0 {
+			handlers.RespondUnauthorized(c, "missing or unverified client certificate")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireClientCert(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/probe", RequireClientCert(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		name   string
+		tls    *tls.ConnectionState
+		status int
+	}{
+		{name: "plain http", tls: nil, status: http.StatusUnauthorized},
+		{name: "no client certificate", tls: &tls.ConnectionState{}, status: http.StatusUnauthorized},
+		{name: "verified client certificate", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}, status: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+			req.TLS = tc.tls
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("status = %d, want %d", w.Code, tc.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"time"
+
+	"backend/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 返回一个按路由记录 HTTP 请求耗时的中间件，结果通过 /metrics 暴露。
+// 路由使用 c.FullPath() 返回的模板，而不是实际的请求路径。
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
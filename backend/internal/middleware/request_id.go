@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 是传递请求 ID 的 HTTP 头。
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey 是请求 ID 在 Gin Context 中的键。
+const RequestIDKey = "requestId"
+
+// maxRequestIDLength 限制调用方传入的请求 ID 长度，避免日志被超长的值污染。
+const maxRequestIDLength = 128
+
+// RequestID 返回一个请求 ID 中间件。
+// 请求头中带有合法的 X-Request-ID 时沿用它（例如反向代理生成的 ID），否则生成一个新的。
+// 请求 ID 保存在 Gin Context 与请求的 context.Context 中，并通过响应头返回，
+// 之后使用 slog.InfoContext 等函数记录的日志都会带上它。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// validRequestID 只接受长度合适、由可见 ASCII 字符组成的请求 ID，防止日志注入。
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID 生成 16 字节的随机请求 ID。
+func newRequestID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf) // crypto/rand.Read 不会返回错误
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RequestID())
+	engine.GET("/probe", func(c *gin.Context) {
+		c.String(http.StatusOK, logging.RequestID(c.Request.Context()))
+	})
+
+	cases := []struct {
+		name   string
+		header string
+		reuse  bool
+	}{
+		{name: "missing", header: "", reuse: false},
+		{name: "from proxy", header: "proxy-abc-123", reuse: true},
+		{name: "control characters", header: "abc\tdef", reuse: false},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLength+1), reuse: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/probe", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			// 响应头与请求 context 中的请求 ID 一致
+			got := w.Header().Get(RequestIDHeader)
+			if got == "" || got != w.Body.String() {
+				t.Fatalf("header %q, context %q, want the same non-empty ID", got, w.Body.String())
+			}
+			if reused := got == tc.header; reused != tc.reuse {
+				t.Fatalf("request ID %q reused = %v, want %v", got, reused, tc.reuse)
+			}
+		})
+	}
+}
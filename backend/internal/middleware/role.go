@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"backend/internal/auth"
+	"backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole 返回一个校验角色的中间件，必须放在 Auth 之后使用。
+// 调用方的角色低于 required 时返回 403。
+func RequireRole(required auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok || !principal.Role.Allows(required) {
+			handlers.RespondError(c, http.StatusForbidden, "forbidden: requires "+string(required)+" role")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration0001InitialSchema 建立引入迁移机制时的完整表结构。
+//
+// 在此之前，表结构由启动时的 AutoMigrate 维护，已有部署的数据库中这些表已经存在。
+// 因此这里对已存在的表执行 AutoMigrate 补齐缺失的列与索引（与旧版启动时的行为一致），
+// 并删除旧版只建在 secret_path 上的唯一索引，使旧数据库平滑过渡到版本 1。
+//
+// 下面的结构体是当时模型的快照，之后 models 包中的结构体变化不会影响这个迁移，
+// 表结构的后续变更应通过新的迁移完成。
+var migration0001InitialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AutoMigrate(&todoItemV1{}, &todoEventV1{}, &notificationOutboxV1{}, &apiTokenV1{}); err != nil {
+			return err
+		}
+		if tx.Migrator().HasIndex(&todoItemV1{}, "idx_todo_items_secret_path") {
+			return tx.Migrator().DropIndex(&todoItemV1{}, "idx_todo_items_secret_path")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&apiTokenV1{}, &notificationOutboxV1{}, &todoEventV1{}, &todoItemV1{})
+	},
+}
+
+type todoItemV1 struct {
+	ID             uint       `gorm:"primaryKey"`
+	ProjectID      string     `gorm:"column:project_id;uniqueIndex:idx_todo_identity,priority:1;not null;default:''"`
+	ProjectName    string     `gorm:"column:project_name;not null;default:''"`
+	Environment    string     `gorm:"column:environment;uniqueIndex:idx_todo_identity,priority:2;not null;default:''"`
+	SecretPath     string     `gorm:"column:secret_path;uniqueIndex:idx_todo_identity,priority:3;not null"`
+	SecretName     string     `gorm:"column:secret_name;not null;default:''"`
+	ReminderNote   string     `gorm:"column:reminder_note;not null;default:''"`
+	IsCompleted    bool       `gorm:"column:is_completed;not null"`
+	CreatedAt      time.Time  `gorm:"column:created_at;not null"`
+	CompletedAt    *time.Time `gorm:"column:completed_at"`
+	CompletedBy    string     `gorm:"column:completed_by;not null;default:''"`
+	CompletionNote string     `gorm:"column:completion_note;not null;default:''"`
+}
+
+func (todoItemV1) TableName() string { return "todo_items" }
+
+type todoEventV1 struct {
+	ID                 uint      `gorm:"primaryKey"`
+	TodoID             uint      `gorm:"column:todo_id;index;not null"`
+	EventType          string    `gorm:"column:event_type;not null"`
+	ReceivedAt         time.Time `gorm:"column:received_at;not null"`
+	InfisicalTimestamp int64     `gorm:"column:infisical_timestamp;not null;default:0"`
+	SourceIP           string    `gorm:"column:source_ip;not null;default:''"`
+	BodyHash           string    `gorm:"column:body_hash;not null;default:''"`
+}
+
+func (todoEventV1) TableName() string { return "todo_events" }
+
+type notificationOutboxV1 struct {
+	ID            uint       `gorm:"primaryKey"`
+	TodoID        uint       `gorm:"column:todo_id;not null;default:0"`
+	Title         string     `gorm:"column:title;not null"`
+	Body          string     `gorm:"column:body;not null"`
+	Status        string     `gorm:"column:status;not null;index:idx_outbox_due,priority:1"`
+	Attempts      int        `gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;not null;index:idx_outbox_due,priority:2"`
+	LastError     string     `gorm:"column:last_error;not null;default:''"`
+	CreatedAt     time.Time  `gorm:"column:created_at;not null"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;not null"`
+	DeliveredAt   *time.Time `gorm:"column:delivered_at"`
+}
+
+func (notificationOutboxV1) TableName() string { return "notification_outbox" }
+
+type apiTokenV1 struct {
+	ID         uint       `gorm:"primaryKey"`
+	Name       string     `gorm:"column:name;not null"`
+	TokenHash  string     `gorm:"column:token_hash;uniqueIndex;not null"`
+	Role       string     `gorm:"column:role;not null;default:'viewer'"`
+	Prefix     string     `gorm:"column:prefix;not null"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+}
+
+func (apiTokenV1) TableName() string { return "api_tokens" }
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// migration0002TodoInsertToken 在 todo_items 上增加 insert_token 列。
+// Webhook 的 upsert 插入新行时写入一个随机值，重置已有行时不修改它，
+// 比较 RETURNING 返回的值就能确定本次是否新建了记录，不再依赖 created_at 是否等于请求时间。
+// 已有的行保持空字符串，不会与任何新生成的值相同。
+var migration0002TodoInsertToken = Migration{
+	Version: 2,
+	Name:    "todo_insert_token",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&todoInsertTokenV2{}, "InsertToken")
+	},
+	// SQLite 的 Migrator.DropColumn 会重建整张表，todo_items 上的索引会随之丢失；
+	// 这一列没有索引，直接使用两种数据库都支持的 ALTER TABLE ... DROP COLUMN（SQLite 3.35+）。
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("ALTER TABLE todo_items DROP COLUMN insert_token").Error
+	},
+}
+
+// todoInsertTokenV2 只包含本次新增的列，用于 AddColumn。
+type todoInsertTokenV2 struct {
+	InsertToken string `gorm:"column:insert_token;not null;default:''"`
+}
+
+func (todoInsertTokenV2) TableName() string { return "todo_items" }
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration0003WebhookNonces 新建 webhook_nonces 表，记录已处理过的 Webhook 签名摘要，
+// 供数据库模式的重放缓存使用。
+var migration0003WebhookNonces = Migration{
+	Version: 3,
+	Name:    "webhook_nonces",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&webhookNonceV3{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&webhookNonceV3{})
+	},
+}
+
+type webhookNonceV3 struct {
+	Digest    string    `gorm:"column:digest;primaryKey"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index"`
+}
+
+func (webhookNonceV3) TableName() string { return "webhook_nonces" }
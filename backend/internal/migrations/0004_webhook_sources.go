@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration0004WebhookSources 新建 webhook_sources 表，保存每个 Webhook 来源的地址标识与 Secret，
+// 并在 todo_items 上增加 source 列，记录待办事项来自哪个来源。
+var migration0004WebhookSources = Migration{
+	Version: 4,
+	Name:    "webhook_sources",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&webhookSourceV4{}); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&todoItemSourceV4{}, "Source"); err != nil {
+			return err
+		}
+		return tx.Migrator().CreateIndex(&todoItemSourceV4{}, "Source")
+	},
+	// SQLite 的 Migrator.DropColumn 会重建整张表，唯一索引 idx_todo_identity 会随之丢失，
+	// 因此删除 source 上的索引后直接使用两种数据库都支持的 ALTER TABLE ... DROP COLUMN（SQLite 3.35+）。
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&todoItemSourceV4{}, "Source"); err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE todo_items DROP COLUMN source").Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&webhookSourceV4{})
+	},
+}
+
+type webhookSourceV4 struct {
+	ID           uint      `gorm:"primaryKey"`
+	Slug         string    `gorm:"column:slug;uniqueIndex;not null"`
+	Name         string    `gorm:"column:name;not null;default:''"`
+	Secret       string    `gorm:"column:secret;not null"`
+	ProjectLabel string    `gorm:"column:project_label;not null;default:''"`
+	Events       string    `gorm:"column:events;not null;default:''"`
+	CreatedAt    time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt    time.Time `gorm:"column:updated_at;not null"`
+}
+
+func (webhookSourceV4) TableName() string { return "webhook_sources" }
+
+// todoItemSourceV4 只包含本次新增的列，用于 AddColumn / CreateIndex。
+type todoItemSourceV4 struct {
+	Source string `gorm:"column:source;index;not null;default:''"`
+}
+
+func (todoItemSourceV4) TableName() string { return "todo_items" }
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migration0005TodoOpenedAt 在 todo_items 上增加 opened_at 列，记录待办事项最近一次变为未完成的时间：
+// 创建时写入，已完成的记录被 Webhook 重置或重新打开时更新。
+// created_at 在重置时保持不变，用它计算“最早的未完成待办事项”会把几个月前创建、刚被重置的记录算得很旧。
+//
+// 已有的记录无法知道当初是何时重新打开的，回填时取最近一次 Webhook 事件的接收时间，
+// 没有事件时取创建时间。
+var migration0005TodoOpenedAt = Migration{
+	Version: 5,
+	Name:    "todo_opened_at",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().AddColumn(&todoOpenedAtV5{}, "OpenedAt"); err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE todo_items SET opened_at = COALESCE(
+			(SELECT MAX(received_at) FROM todo_events WHERE todo_events.todo_id = todo_items.id),
+			created_at)`).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().CreateIndex(&todoOpenedAtV5{}, "OpenedAt")
+	},
+	// SQLite 的 Migrator.DropColumn 会重建整张表并丢失其他索引，先删除本列的索引，再直接 DROP COLUMN。
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&todoOpenedAtV5{}, "OpenedAt"); err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE todo_items DROP COLUMN opened_at").Error
+	},
+}
+
+// todoOpenedAtV5 只包含本次新增的列，用于 AddColumn。
+// SQLite 不能给已有的表添加没有默认值的 NOT NULL 列，因此列本身允许 NULL，回填后每一行都有值。
+type todoOpenedAtV5 struct {
+	OpenedAt *time.Time `gorm:"column:opened_at;index"`
+}
+
+func (todoOpenedAtV5) TableName() string { return "todo_items" }
@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// migration0006OutboxTraceParent 在 notification_outbox 上增加 trace_parent 列，
+// 保存入队时的 W3C traceparent，后台 Worker 发送通知时据此接上 Webhook 请求的链路。
+var migration0006OutboxTraceParent = Migration{
+	Version: 6,
+	Name:    "outbox_trace_parent",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&outboxTraceParentV6{}, "TraceParent")
+	},
+	// SQLite 的 Migrator.DropColumn 会重建整张表，索引 idx_outbox_due 会随之丢失，
+	// 这一列没有索引，直接使用两种数据库都支持的 ALTER TABLE ... DROP COLUMN（SQLite 3.35+）。
+	Down: func(tx *gorm.DB) error {
+		return tx.Exec("ALTER TABLE notification_outbox DROP COLUMN trace_parent").Error
+	},
+}
+
+// outboxTraceParentV6 只包含本次新增的列，用于 AddColumn。
+type outboxTraceParentV6 struct {
+	TraceParent string `gorm:"column:trace_parent;not null;default:''"`
+}
+
+func (outboxTraceParentV6) TableName() string { return "notification_outbox" }
@@ -0,0 +1,96 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RunCommand 实现 migrate 子命令，args 是 migrate 之后的参数，结果输出到 out。
+//
+//	migrate up        执行所有尚未执行的迁移
+//	migrate down [n]  回滚最近执行的 n 个迁移，默认 1 个
+//	migrate status    列出所有迁移及其执行状态
+func RunCommand(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("用法: migrate up | down [n] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := Up(db)
+		for _, m := range applied {
+			fmt.Fprintf(out, "已执行 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "没有需要执行的迁移")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("回滚数量无效: %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := Down(db, steps)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "已回滚 %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "没有可以回滚的迁移")
+		}
+		return nil
+
+	case "status":
+		return printStatus(db, out)
+
+	default:
+		return fmt.Errorf("未知的 migrate 子命令: %q", args[0])
+	}
+}
+
+// printStatus 以表格形式输出迁移状态。
+func printStatus(db *gorm.DB, out io.Writer) error {
+	statuses, err := List(db)
+	if err != nil {
+		return err
+	}
+	current, err := Current(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n数据库版本: %d，程序支持的最新版本: %d\n", current, Latest())
+	if current > Latest() {
+		fmt.Fprintln(out, "警告: 数据库版本比程序新，服务将拒绝启动")
+	}
+	return nil
+}
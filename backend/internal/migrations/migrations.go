@@ -0,0 +1,225 @@
+// Package migrations 管理数据库表结构的版本。
+// 每个迁移都有递增的版本号以及对应的 Up / Down 操作，已执行的版本记录在 schema_migrations 表中。
+// 与 AutoMigrate 不同，迁移可以重命名列、回填数据、调整索引，并且可以回滚。
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 描述一次表结构变更。
+// Up 与 Down 在同一个事务中执行，并与 schema_migrations 的记录一起提交。
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// registry 按版本号升序列出所有迁移。新增迁移时追加到末尾，已发布的迁移不能再修改。
+var registry = []Migration{
+	migration0001InitialSchema,
+	migration0002TodoInsertToken,
+	migration0003WebhookNonces,
+	migration0004WebhookSources,
+	migration0005TodoOpenedAt,
+	migration0006OutboxTraceParent,
+}
+
+// ErrSchemaTooNew 表示数据库的表结构版本比当前程序认识的最新版本还要新，
+// 通常是因为回退到了旧版本的程序。继续运行可能损坏数据，因此拒绝启动。
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// schemaMigration 是 schema_migrations 表中的一条记录。
+type schemaMigration struct {
+	Version   int       `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;not null"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+// TableName 指定表名。
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 描述一个迁移的执行状态。
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time // 未执行时为 nil
+}
+
+// Latest 返回当前程序认识的最新版本号。
+func Latest() int {
+	return registry[len(registry)-1].Version
+}
+
+// Current 返回数据库当前的表结构版本，尚未执行过任何迁移时返回 0。
+func Current(db *gorm.DB) (int, error) {
+	if err := ensureTable(db); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Check 检查数据库的表结构版本，返回尚未执行的迁移数量。
+// 数据库版本比程序认识的还要新时返回 ErrSchemaTooNew。
+func Check(db *gorm.DB) (pending int, err error) {
+	current, err := Current(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > Latest() {
+		return 0, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, Latest())
+	}
+	for _, m := range registry {
+		if m.Version > current {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up 依次执行所有尚未执行的迁移，返回成功执行的迁移。
+func Up(db *gorm.DB) ([]Migration, error) {
+	if _, err := Check(db); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range registry {
+		ran, err := apply(db, m, true)
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		if ran {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// Down 按版本号倒序回滚最近执行的 steps 个迁移，返回成功回滚的迁移。
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	if _, err := Check(db); err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(registry) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := registry[i]
+		ran, err := apply(db, m, false)
+		if err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+		if ran {
+			reverted = append(reverted, m)
+		}
+	}
+	return reverted, nil
+}
+
+// List 返回所有迁移及其执行状态，按版本号升序排列。
+func List(db *gorm.DB) ([]Status, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	var records []schemaMigration
+	if err := db.Order("version asc").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[int]time.Time, len(records))
+	for _, record := range records {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	statuses := make([]Status, 0, len(registry))
+	for _, m := range registry {
+		status := Status{Version: m.Version, Name: m.Name}
+		if at, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	// 数据库中还可能有更新版本的程序执行过的迁移，一并列出
+	for _, record := range records {
+		if record.Version > Latest() {
+			at := record.AppliedAt
+			statuses = append(statuses, Status{Version: record.Version, Name: record.Name, AppliedAt: &at})
+		}
+	}
+	return statuses, nil
+}
+
+// apply 在事务中执行单个迁移的 Up 或 Down，并更新 schema_migrations。
+// 迁移已处于目标状态时跳过，返回 false。
+// PostgreSQL 上会先获取事务级的 advisory lock，避免多个实例同时启动时重复执行迁移；
+// SQLite 只有一个连接，天然是串行的。
+func apply(db *gorm.DB, m Migration, up bool) (ran bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx); err != nil {
+			return err
+		}
+
+		// 拿到锁之后再检查一次，其他实例可能已经执行过了
+		var count int64
+		if err := tx.Model(&schemaMigration{}).Where("version = ?", m.Version).Count(&count).Error; err != nil {
+			return err
+		}
+		if (count > 0) == up {
+			return nil
+		}
+
+		if up {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			ran = true
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		}
+
+		if err := m.Down(tx); err != nil {
+			return err
+		}
+		ran = true
+		return tx.Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+	})
+	return ran, err
+}
+
+// advisoryLockID 是迁移使用的 PostgreSQL advisory lock 标识，任意固定值即可。
+const advisoryLockID = 7370641
+
+// lock 在 PostgreSQL 上获取迁移的事务级 advisory lock，事务结束时自动释放。
+func lock(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockID).Error
+}
+
+// ensureTable 确保 schema_migrations 表存在。
+// 多个实例同时连接空数据库时都会发现表不存在，因此建表同样在 advisory lock 下进行，
+// 拿到锁之后再检查一次。
+func ensureTable(db *gorm.DB) error {
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx); err != nil {
+			return err
+		}
+		if tx.Migrator().HasTable(&schemaMigration{}) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&schemaMigration{})
+	})
+}
@@ -0,0 +1,183 @@
+package migrations_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/migrations"
+	"backend/internal/models"
+	"backend/internal/repo"
+	"backend/internal/testdb"
+
+	"gorm.io/gorm"
+)
+
+func TestUpConcurrent(t *testing.T) {
+	testdb.RunUnmigrated(t, func(t *testing.T, database *gorm.DB) {
+		// 多个实例同时对空数据库执行迁移：advisory lock 保证每个迁移只执行一次，
+		// 没有实例因为建表或写 schema_migrations 冲突而失败
+		const instances = 4
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			applied = map[int]int{}
+		)
+		for i := 0; i < instances; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ran, err := migrations.Up(database)
+				if err != nil {
+					t.Errorf("Up: %v", err)
+					return
+				}
+				mu.Lock()
+				for _, m := range ran {
+					applied[m.Version]++
+				}
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		if len(applied) != migrations.Latest() {
+			t.Fatalf("applied %d distinct migrations, want %d", len(applied), migrations.Latest())
+		}
+		for version, count := range applied {
+			if count != 1 {
+				t.Errorf("migration %04d applied %d times", version, count)
+			}
+		}
+		if pending, err := migrations.Check(database); err != nil || pending != 0 {
+			t.Fatalf("Check = %d, %v; want 0 pending", pending, err)
+		}
+	})
+}
+
+func TestDownUp(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		// 每个迁移的 Down 都能执行，回滚后可以重新迁移到最新版本
+		reverted, err := migrations.Down(database, migrations.Latest())
+		if err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		if len(reverted) != migrations.Latest() {
+			t.Fatalf("reverted %d migrations, want %d", len(reverted), migrations.Latest())
+		}
+		if current, err := migrations.Current(database); err != nil || current != 0 {
+			t.Fatalf("Current after Down = %d, %v; want 0", current, err)
+		}
+
+		if _, err := migrations.Up(database); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if current, err := migrations.Current(database); err != nil || current != migrations.Latest() {
+			t.Fatalf("Current after Up = %d, %v; want %d", current, err, migrations.Latest())
+		}
+	})
+}
+
+func TestDownKeepsIndexes(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		// 逐个回滚到初始版本，每一步之后 0001 创建的索引都应该还在
+		for version := migrations.Latest(); version > 1; version-- {
+			if _, err := migrations.Down(database, 1); err != nil {
+				t.Fatalf("Down from %d: %v", version, err)
+			}
+			if !database.Migrator().HasIndex(&models.TodoItem{}, "idx_todo_identity") {
+				t.Fatalf("idx_todo_identity missing after rolling back to %d", version-1)
+			}
+			if !database.Migrator().HasIndex(&models.NotificationOutbox{}, "idx_outbox_due") {
+				t.Fatalf("idx_outbox_due missing after rolling back to %d", version-1)
+			}
+		}
+	})
+}
+
+// legacyTodoItem 是引入迁移机制之前、只按路径识别待办事项时由 AutoMigrate 建立的表结构。
+type legacyTodoItem struct {
+	ID          uint       `gorm:"primaryKey"`
+	SecretPath  string     `gorm:"column:secret_path;uniqueIndex;not null"`
+	IsCompleted bool       `gorm:"column:is_completed;not null"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null"`
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+}
+
+func (legacyTodoItem) TableName() string { return "todo_items" }
+
+func TestUpgradeClaimsLegacyTodos(t *testing.T) {
+	testdb.RunUnmigrated(t, func(t *testing.T, database *gorm.DB) {
+		// 旧版本的数据库：一条已完成的待办事项，只有路径
+		if err := database.Migrator().CreateTable(&legacyTodoItem{}); err != nil {
+			t.Fatalf("create legacy table: %v", err)
+		}
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		legacy := legacyTodoItem{SecretPath: "/db", IsCompleted: true, CreatedAt: created, CompletedAt: &created}
+		if err := database.Create(&legacy).Error; err != nil {
+			t.Fatalf("insert legacy todo: %v", err)
+		}
+
+		if _, err := migrations.Up(database); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+
+		if database.Migrator().HasIndex(&models.TodoItem{}, "idx_todo_items_secret_path") {
+			t.Fatal("legacy secret_path index still exists")
+		}
+
+		// 升级后第一个带真实项目的 Webhook 重置这条记录，不产生重复的待办事项
+		todos := repo.NewTodoRepository(database)
+		event := repo.WebhookEvent{EventType: "secrets.modified"}
+		fields := repo.TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db"}
+		item, isNew, err := todos.UpsertFromWebhook(fields, event, nil, created.AddDate(0, 1, 0))
+		if err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+		if isNew || item.ID != legacy.ID || item.IsCompleted || item.ProjectID != "p1" {
+			t.Fatalf("UpsertFromWebhook = %+v created %v, want legacy todo %d reopened", item, isNew, legacy.ID)
+		}
+
+		// 旧的唯一索引已删除，其他环境下的同名路径可以单独建立记录
+		fields.Environment = "dev"
+		if _, _, err := todos.UpsertFromWebhook(fields, event, nil, created); err != nil {
+			t.Fatalf("UpsertFromWebhook for another environment: %v", err)
+		}
+		var count int64
+		if err := database.Model(&models.TodoItem{}).Count(&count).Error; err != nil || count != 2 {
+			t.Fatalf("todo rows = %d, %v; want 2", count, err)
+		}
+	})
+}
+
+func TestTodoOpenedAtBackfill(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		// 回滚到 0005 之前，todo_items 还没有 opened_at 列
+		if _, err := migrations.Down(database, migrations.Latest()-4); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+
+		// 升级前的数据：a 有两条 Webhook 事件，b 没有事件
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		lastEvent := created.AddDate(0, 1, 0)
+		if err := database.Exec(`INSERT INTO todo_items (id, secret_path, is_completed, created_at) VALUES (1, '/a', ?, ?), (2, '/b', ?, ?)`,
+			false, created, false, created).Error; err != nil {
+			t.Fatalf("insert todos: %v", err)
+		}
+		if err := database.Exec(`INSERT INTO todo_events (todo_id, event_type, received_at) VALUES (1, 'secrets.modified', ?), (1, 'secrets.modified', ?)`,
+			created, lastEvent).Error; err != nil {
+			t.Fatalf("insert events: %v", err)
+		}
+
+		if _, err := migrations.Up(database); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		var todos []models.TodoItem
+		if err := database.Order("id").Find(&todos).Error; err != nil {
+			t.Fatalf("find todos: %v", err)
+		}
+		if len(todos) != 2 || !todos[0].OpenedAt.Equal(lastEvent) || !todos[1].OpenedAt.Equal(created) {
+			t.Fatalf("opened_at = %+v, want %v and %v", todos, lastEvent, created)
+		}
+	})
+}
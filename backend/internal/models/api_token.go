@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// APIToken 代表一个用于调用 API 的 Bearer Token。
+// 数据库中只保存 Token 的哈希，明文只在创建时返回一次。
+type APIToken struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Name 是 Token 的用途说明，例如 "grafana" 或 "ci"。
+	Name string `gorm:"column:name;not null"`
+
+	// TokenHash 是 Token 明文的 SHA-256 摘要，建立唯一索引以便按哈希查找。
+	TokenHash string `gorm:"column:token_hash;uniqueIndex;not null"`
+
+	// Role 是 Token 的权限等级：viewer / operator / admin。
+	// 默认值为最小权限 viewer。
+	Role string `gorm:"column:role;not null;default:'viewer'"`
+
+	// Prefix 是 Token 明文的前几位，仅用于展示和辨认。
+	Prefix string `gorm:"column:prefix;not null"`
+
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+
+	// LastUsedAt 记录最近一次使用时间，从未使用时为 NULL。
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+}
+
+// TableName 自定义表名为 api_tokens。
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
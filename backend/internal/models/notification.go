@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// 通知发件箱 (Outbox) 中消息的状态。
+const (
+	// OutboxStatusPending 表示等待发送或等待重试。
+	OutboxStatusPending = "pending"
+	// OutboxStatusSending 表示已被某个 Worker 认领、正在发送。
+	// 认领有期限（记录在 NextAttemptAt 中），Worker 崩溃后消息会在期限过后被重新认领。
+	OutboxStatusSending = "sending"
+	// OutboxStatusDelivered 表示已成功发送。
+	OutboxStatusDelivered = "delivered"
+	// OutboxStatusDead 表示重试次数耗尽，进入死信状态，需要人工重新入队。
+	OutboxStatusDead = "dead"
+)
+
+// NotificationOutbox 代表通知发件箱中的一条待发送消息。
+// Webhook 入库后先把通知写入该表，再由后台 Worker 异步发送，
+// 这样即使 Apprise 暂时不可用，提醒也不会丢失。
+type NotificationOutbox struct {
+	ID uint `gorm:"primaryKey"`
+
+	// TodoID 关联的待办事项 ID，测试事件为 0。
+	TodoID uint `gorm:"column:todo_id;not null;default:0"`
+
+	// Title 与 Body 是已经渲染好的通知内容。
+	Title string `gorm:"column:title;not null"`
+	Body  string `gorm:"column:body;not null"`
+
+	// Status 取值见 OutboxStatus* 常量。
+	// 与 NextAttemptAt 组成复合索引，方便 Worker 查询到期的消息。
+	Status string `gorm:"column:status;not null;index:idx_outbox_due,priority:1"`
+
+	// Attempts 记录已尝试发送的次数，Worker 认领消息时加一。
+	// 更新发送结果时以它为条件，认领过期后被其他 Worker 重新认领的消息不会被旧的结果覆盖。
+	Attempts int `gorm:"column:attempts;not null;default:0"`
+
+	// NextAttemptAt 记录下一次允许发送的时间，用于实现指数退避。
+	// 状态为 sending 时表示认领的截止时间，过期后其他 Worker 可以重新认领。
+	NextAttemptAt time.Time `gorm:"column:next_attempt_at;not null;index:idx_outbox_due,priority:2"`
+
+	// TraceParent 保存入队时所在链路的 W3C traceparent，未启用链路追踪时为空字符串。
+	// Worker 发送通知时据此把发送过程接到触发它的 Webhook 请求的 Trace 上。
+	TraceParent string `gorm:"column:trace_parent;not null;default:''"`
+
+	// LastError 记录最近一次发送失败的原因。
+	LastError string `gorm:"column:last_error;not null;default:''"`
+
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+
+	// DeliveredAt 记录发送成功的时间，未发送时为 NULL。
+	DeliveredAt *time.Time `gorm:"column:delivered_at"`
+}
+
+// TableName 自定义表名为 notification_outbox。
+func (NotificationOutbox) TableName() string {
+	return "notification_outbox"
+}
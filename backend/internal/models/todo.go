@@ -11,11 +11,29 @@ type TodoItem struct {
 	// `gorm:"primaryKey"` 显式声明这是主键。
 	ID uint `gorm:"primaryKey"`
 
+	// ProjectID 存储 Infisical 项目 ID。
+	// 与 Environment、SecretPath 共同组成唯一索引 idx_todo_identity，
+	// 这样不同项目或环境下的同名路径不会互相覆盖。
+	// 手动创建的待办事项可以不填，默认为空字符串。
+	ProjectID string `gorm:"column:project_id;uniqueIndex:idx_todo_identity,priority:1;not null;default:''"`
+
+	// ProjectName 存储 Infisical 项目名称，仅用于展示。
+	ProjectName string `gorm:"column:project_name;not null;default:''"`
+
+	// Environment 存储 Infisical 环境标识，例如 "dev"、"prod"。
+	Environment string `gorm:"column:environment;uniqueIndex:idx_todo_identity,priority:2;not null;default:''"`
+
 	// SecretPath 存储密钥路径，例如 "/dev/db/password"。
 	// `gorm:"column:secret_path"` 指定数据库中的列名为 secret_path。
-	// `uniqueIndex` 创建唯一索引，确保同一个路径只能有一条记录。
+	// `uniqueIndex:idx_todo_identity` 将其加入复合唯一索引，确保同一个项目 + 环境 + 路径只能有一条记录。
 	// `not null` 约束该字段不能为空。
-	SecretPath string `gorm:"column:secret_path;uniqueIndex;not null"`
+	SecretPath string `gorm:"column:secret_path;uniqueIndex:idx_todo_identity,priority:3;not null"`
+
+	// SecretName 存储触发变更的密钥名称，仅用于展示。
+	SecretName string `gorm:"column:secret_name;not null;default:''"`
+
+	// ReminderNote 存储 Infisical 中配置的提醒备注。
+	ReminderNote string `gorm:"column:reminder_note;not null;default:''"`
 
 	// IsCompleted 标记该待办事项是否已完成。
 	// 使用 bool 类型，SQLite 中会存储为 0 或 1。
@@ -25,10 +43,29 @@ type TodoItem struct {
 	// GORM 约定：如果字段名为 CreatedAt，它会在创建记录时自动填充当前时间。
 	CreatedAt time.Time `gorm:"column:created_at;not null"`
 
+	// OpenedAt 记录最近一次变为未完成的时间：创建时等于 CreatedAt，
+	// 已完成的记录被 Webhook 重置或重新打开时更新为当时的时间，未完成的记录再次收到 Webhook 时保持不变。
+	OpenedAt time.Time `gorm:"column:opened_at;index"`
+
 	// CompletedAt 记录完成时间。
 	// 使用指针类型 *time.Time 是为了支持 NULL 值。
 	// 如果该字段是 nil，数据库中存储为 NULL，表示尚未完成。
 	CompletedAt *time.Time `gorm:"column:completed_at"`
+
+	// CompletedBy 记录标记完成的操作者（API Token 名称或 OIDC 用户名）。
+	// 未完成时为空字符串。
+	CompletedBy string `gorm:"column:completed_by;not null;default:''"`
+
+	// CompletionNote 记录完成时填写的备注，例如已经同步更新了哪些服务。
+	CompletionNote string `gorm:"column:completion_note;not null;default:''"`
+
+	// InsertToken 是 Webhook 插入这条记录时写入的随机值，重置记录时保持不变。
+	// UpsertFromWebhook 据此判断本次是新建还是重置，不对外暴露。
+	InsertToken string `gorm:"column:insert_token;not null;default:''"`
+
+	// Source 记录待办事项来自哪个 Webhook 来源（WebhookSource 的 Slug）。
+	// 通过 /api/todos/webhook 或手动创建的待办事项为空字符串。
+	Source string `gorm:"column:source;index;not null;default:''"`
 }
 
 // TableName 实现 GORM 的 Tabler 接口，用于自定义表名。
@@ -36,4 +73,4 @@ type TodoItem struct {
 // 这里显式返回 "todo_items" 只是为了明确性。
 func (TodoItem) TableName() string {
 	return "todo_items"
-}
\ No newline at end of file
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TodoEvent 代表一次被接受的 Webhook 投递记录。
+// 每当 Webhook 创建或重置某个待办事项时都会追加一条，
+// 从而保留密钥的完整变更时间线，不会因为 Upsert 覆盖状态而丢失。
+type TodoEvent struct {
+	// ID 是主键。
+	ID uint `gorm:"primaryKey"`
+
+	// TodoID 关联的待办事项 ID。
+	// 建立普通索引，方便按待办事项查询时间线。
+	TodoID uint `gorm:"column:todo_id;index;not null"`
+
+	// EventType 记录 Infisical 的事件类型，例如 "secrets.modified"。
+	EventType string `gorm:"column:event_type;not null"`
+
+	// ReceivedAt 记录后端接收到该 Webhook 的时间。
+	ReceivedAt time.Time `gorm:"column:received_at;not null"`
+
+	// InfisicalTimestamp 记录载荷中 Infisical 自带的 timestamp 字段（原样保存）。
+	InfisicalTimestamp int64 `gorm:"column:infisical_timestamp;not null;default:0"`
+
+	// SourceIP 记录请求来源 IP。
+	SourceIP string `gorm:"column:source_ip;not null;default:''"`
+
+	// BodyHash 记录原始请求体的 SHA-256 十六进制摘要，便于事后比对载荷而无需保存明文。
+	BodyHash string `gorm:"column:body_hash;not null;default:''"`
+}
+
+// TableName 自定义表名为 todo_events。
+func (TodoEvent) TableName() string {
+	return "todo_events"
+}
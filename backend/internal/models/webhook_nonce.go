@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// WebhookNonce 记录一个已经处理过的 Webhook 签名摘要。
+// 启用数据库重放缓存时使用，服务重启后仍能识别窗口期内重放的请求。
+type WebhookNonce struct {
+	// Digest 是签名摘要的十六进制形式。
+	Digest string `gorm:"column:digest;primaryKey"`
+
+	// ExpiresAt 是记录的过期时间，过期后同一个摘要可以再次被接受。
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index"`
+}
+
+// TableName 自定义表名为 webhook_nonces。
+func (WebhookNonce) TableName() string {
+	return "webhook_nonces"
+}
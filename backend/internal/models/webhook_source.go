@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookSource 代表一个 Webhook 来源，通常对应一个 Infisical 项目。
+// 每个来源有自己的地址 /api/webhooks/{slug} 和签名 Secret，互不影响。
+type WebhookSource struct {
+	ID uint `gorm:"primaryKey"`
+
+	// Slug 是来源在 URL 中的标识，例如 "payments-prod"，建立唯一索引以便按地址查找。
+	Slug string `gorm:"column:slug;uniqueIndex;not null"`
+
+	// Name 是来源的展示名称。
+	Name string `gorm:"column:name;not null;default:''"`
+
+	// Secret 是该来源的 Webhook Secret。
+	// 验证签名需要用明文重新计算 HMAC，因此这里保存明文，接口只在创建时返回一次。
+	Secret string `gorm:"column:secret;not null"`
+
+	// ProjectLabel 是默认项目名，载荷中没有 projectName 时使用。
+	ProjectLabel string `gorm:"column:project_label;not null;default:''"`
+
+	// Events 是允许的事件类型，逗号分隔，为空表示接受所有支持的事件。
+	Events string `gorm:"column:events;not null;default:''"`
+
+	CreatedAt time.Time `gorm:"column:created_at;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at;not null"`
+}
+
+// TableName 自定义表名为 webhook_sources。
+func (WebhookSource) TableName() string {
+	return "webhook_sources"
+}
+
+// EventList 返回允许的事件类型列表，为空表示不限制。
+func (s WebhookSource) EventList() []string {
+	var events []string
+	for _, event := range strings.Split(s.Events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// AllowsEvent 判断该来源是否接受指定的事件类型。
+func (s WebhookSource) AllowsEvent(event string) bool {
+	events := s.EventList()
+	if len(events) == 0 {
+		return true
+	}
+	for _, allowed := range events {
+		if allowed == event {
+			return true
+		}
+	}
+	return false
+}
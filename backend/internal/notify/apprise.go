@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"backend/internal/tracing"
+)
+
+// appriseTimeout 是单次调用 Apprise API 的超时时间。
+const appriseTimeout = 10 * time.Second
+
+// AppriseNotifier 通过 Apprise API 推送通知。
+// Apprise 是一个通知聚合服务，一次请求即可推送到 Telegram、Bark、邮件等多个渠道。
+type AppriseNotifier struct {
+	targets atomic.Pointer[appriseTargets]
+	client  *http.Client
+}
+
+// appriseTargets 是推送的目标，重新加载配置时整体替换。
+type appriseTargets struct {
+	endpoint         string // Apprise API 地址
+	notificationURLs string // Apprise 目标 URL 列表（按 Apprise 规范填写）
+}
+
+// NewAppriseNotifier 创建 AppriseNotifier 实例。
+func NewAppriseNotifier(endpoint, notificationURLs string) *AppriseNotifier {
+	// 经过 tracing.Transport 的请求会记录为链路追踪中的一个 Span
+	client := &http.Client{Timeout: appriseTimeout, Transport: tracing.Transport(http.DefaultTransport)}
+	n := &AppriseNotifier{client: client}
+	n.SetTargets(endpoint, notificationURLs)
+	return n
+}
+
+// SetTargets 替换推送目标，之后发送的通知（包括发件箱中等待重试的）使用新的目标。
+func (n *AppriseNotifier) SetTargets(endpoint, notificationURLs string) {
+	n.targets.Store(&appriseTargets{
+		endpoint:         strings.TrimSpace(endpoint),
+		notificationURLs: strings.TrimSpace(notificationURLs),
+	})
+}
+
+// Notify 将消息以 JSON 形式 POST 到 Apprise API。
+// Apprise 返回非 2xx 状态码时视为失败，并把响应体带入错误信息便于排查。
+func (n *AppriseNotifier) Notify(ctx context.Context, msg Message) error {
+	targets := n.targets.Load()
+	payload := map[string]string{
+		"urls":  targets.notificationURLs,
+		"body":  msg.Body,
+		"title": msg.Title,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, targets.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		bodyText, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("apprise status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyText)))
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAppriseNotifier(t *testing.T) {
+	var received map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("no targets reachable\n"))
+	}))
+	defer server.Close()
+
+	notifier := NewAppriseNotifier(" "+server.URL+" ", " tgram://token/chat ")
+	if err := notifier.Notify(context.Background(), Message{Title: "title", Body: "body"}); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if received["urls"] != "tgram://token/chat" || received["title"] != "title" || received["body"] != "body" {
+		t.Fatalf("payload = %v", received)
+	}
+
+	// 非 2xx 视为失败，错误中带上 Apprise 的响应便于排查
+	status = http.StatusFailedDependency
+	err := notifier.Notify(context.Background(), Message{Title: "title", Body: "body"})
+	if err == nil || !strings.Contains(err.Error(), "424") || !strings.Contains(err.Error(), "no targets reachable") {
+		t.Fatalf("Notify = %v, want an error with the status and response body", err)
+	}
+}
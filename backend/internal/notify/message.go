@@ -0,0 +1,45 @@
+package notify
+
+import (
+	_ "embed"
+	"strings"
+	"text/template"
+)
+
+// 事件类型与 handlers 包保持一致。
+const eventTest = "test"
+
+// messageTemplate 在编译期嵌入，避免运行时依赖模板文件。
+//
+//go:embed message.md
+var messageTemplate string
+
+// parsedTemplate 在包初始化时解析一次，模板有语法错误会直接 panic，便于尽早发现问题。
+var parsedTemplate = template.Must(template.New("message").Option("missingkey=error").Parse(messageTemplate))
+
+// MessageData 是渲染通知模板所需的数据。
+type MessageData struct {
+	TodoID       uint
+	Event        string
+	ProjectName  string
+	Environment  string
+	SecretPath   string
+	SecretName   string
+	ReminderNote string
+}
+
+// NewMessage 根据事件数据渲染出一条通知。
+// 测试事件使用单独的标题，方便在通知渠道中区分。
+func NewMessage(data MessageData) (Message, error) {
+	var builder strings.Builder
+	if err := parsedTemplate.Execute(&builder, data); err != nil {
+		return Message{}, err
+	}
+
+	title := "Infisical secrets updated"
+	if data.Event == eventTest {
+		title = "Infisical webhook test"
+	}
+
+	return Message{TodoID: data.TodoID, Title: title, Body: builder.String()}, nil
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage(MessageData{
+		Event:        "secrets.modified",
+		ProjectName:  "billing",
+		Environment:  "prod",
+		SecretPath:   "/db",
+		SecretName:   "PASSWORD",
+		ReminderNote: "rotate the replica too",
+	})
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	if msg.Title != "Infisical secrets updated" {
+		t.Fatalf("Title = %q", msg.Title)
+	}
+	for _, want := range []string{"- Event: `secrets.modified`", "- Project: `billing`", "- Environment: `prod`", "- Path: `/db`", "- Secret: `PASSWORD`", "- Note: rotate the replica too"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("Body does not contain %q:\n%s", want, msg.Body)
+		}
+	}
+
+	// 空的可选字段不输出对应的行，测试事件使用单独的标题
+	msg, err = NewMessage(MessageData{Event: eventTest, SecretPath: "/"})
+	if err != nil {
+		t.Fatalf("NewMessage: %v", err)
+	}
+	if msg.Title != "Infisical webhook test" {
+		t.Fatalf("Title = %q, want the test title", msg.Title)
+	}
+	for _, unwanted := range []string{"Project:", "Environment:", "Secret:", "Note:"} {
+		if strings.Contains(msg.Body, unwanted) {
+			t.Errorf("Body contains %q for an empty field:\n%s", unwanted, msg.Body)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package notify 负责将密钥变更事件推送到外部通知渠道。
+// 它整合了原 notification 目录中 Appwrite Function 的 Apprise 转发逻辑，
+// 使后端一个服务即可同时提供待办清单与推送提醒。
+package notify
+
+import (
+	"context"
+
+	"backend/internal/repo"
+	"backend/internal/tracing"
+)
+
+// Message 是一条待发送的通知。
+type Message struct {
+	// TodoID 关联的待办事项 ID，测试事件为 0。
+	TodoID uint
+	Title  string
+	Body   string
+}
+
+// OutboxMessage 把消息转换为发件箱记录的内容，ctx 中的链路信息随消息一起保存。
+func (m Message) OutboxMessage(ctx context.Context) repo.OutboxMessage {
+	return repo.OutboxMessage{Title: m.Title, Body: m.Body, TraceParent: tracing.TraceParent(ctx)}
+}
+
+// Notifier 定义了通知发送者需要实现的接口。
+// 发件箱 Worker 只依赖这个接口，不关心具体的推送渠道（Apprise、邮件等）。
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
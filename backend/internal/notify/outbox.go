@@ -0,0 +1,206 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/repo"
+	"backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outbox 的默认参数。
+const (
+	defaultPollInterval = 5 * time.Second
+	defaultBaseDelay    = 10 * time.Second
+	defaultMaxDelay     = 30 * time.Minute
+	defaultBatchSize    = 20
+
+	// defaultClaimLease 是 Worker 认领一批消息的期限，需要大于发送一整批消息的最长耗时
+	// （defaultBatchSize 次 Apprise 调用，每次最多 appriseTimeout）。
+	defaultClaimLease = 5 * time.Minute
+)
+
+// OutboxOptions 控制发件箱 Worker 的重试策略。
+type OutboxOptions struct {
+	// MaxAttempts 是单条消息的最大发送次数，超过后进入死信状态。
+	MaxAttempts int
+	// PollInterval 是 Worker 轮询到期消息的间隔。
+	PollInterval time.Duration
+	// BaseDelay 是第一次重试前的等待时间，之后每次翻倍。
+	BaseDelay time.Duration
+	// MaxDelay 是重试等待时间的上限。
+	MaxDelay time.Duration
+}
+
+// Outbox 是一个持久化的 Notifier。
+// Notify 只负责把消息写入数据库中的发件箱，由 Run 启动的后台 Worker
+// 通过真正的 sender（如 AppriseNotifier）发送，失败时按指数退避重试，
+// 重试次数耗尽后进入死信状态，等待人工重新入队。
+type Outbox struct {
+	repo   *repo.OutboxRepository
+	sender Notifier
+	opts   OutboxOptions
+	wake   chan struct{} // 有新消息入队时唤醒 Worker，避免等待下一次轮询
+}
+
+// NewOutbox 创建 Outbox 实例，未设置的选项使用默认值。
+func NewOutbox(repo *repo.OutboxRepository, sender Notifier, opts OutboxOptions) *Outbox {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.BaseDelay <= 0 {
+		opts.BaseDelay = defaultBaseDelay
+	}
+	if opts.MaxDelay <= 0 {
+		opts.MaxDelay = defaultMaxDelay
+	}
+	return &Outbox{
+		repo:   repo,
+		sender: sender,
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Notify 将消息写入发件箱并唤醒 Worker。
+// 只要写库成功就返回 nil，实际发送结果由 Worker 负责跟踪。
+// ctx 中的链路信息随消息一起保存，Worker 发送时接着这条链路记录。
+func (o *Outbox) Notify(ctx context.Context, msg Message) error {
+	if _, err := o.repo.WithContext(ctx).Enqueue(msg.TodoID, msg.OutboxMessage(ctx), time.Now().UTC()); err != nil {
+		return err
+	}
+	o.Wake()
+	return nil
+}
+
+// Wake 通知 Worker 立即检查一次发件箱。
+// 重新入队死信消息后也应调用它。
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+		// 已有待处理的唤醒信号，无需重复发送
+	}
+}
+
+// Run 启动 Worker 循环，直到 ctx 被取消。
+// 它是阻塞调用，通常在单独的 goroutine 中运行。
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// drain 认领并发送所有已到期的消息，直到没有到期消息或 ctx 被取消。
+// 多个 Worker 共用同一个数据库时，每条消息只会被其中一个 Worker 认领。
+func (o *Outbox) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		items, err := o.repo.ClaimDue(time.Now().UTC(), defaultClaimLease, defaultBatchSize)
+		if err != nil {
+			slog.Error("认领通知发件箱中的消息失败", "error", err)
+			return
+		}
+		if len(items) == 0 {
+			return
+		}
+
+		for i, item := range items {
+			if ctx.Err() != nil {
+				o.release(items[i:])
+				return
+			}
+
+			// 认领时 Attempts 已经加一，就是本次发送的次数
+			sendErr := o.send(ctx, item, item.Attempts)
+			if sendErr != nil && ctx.Err() != nil {
+				// 服务正在关闭，发送被中断：放弃认领，下次启动后重新发送，不计入发送次数
+				o.release(items[i:])
+				return
+			}
+			now := time.Now().UTC()
+
+			var err error
+			switch {
+			case sendErr == nil:
+				err = o.repo.MarkDelivered(item.ID, item.Attempts, now)
+			case item.Attempts >= o.opts.MaxAttempts:
+				slog.Error("通知发送失败，已进入死信状态", "outbox_id", item.ID, "attempts", item.Attempts, "error", sendErr)
+				err = o.repo.MarkDead(item.ID, item.Attempts, sendErr.Error(), now)
+			default:
+				delay := o.backoff(item.Attempts)
+				slog.Warn("通知发送失败，稍后重试", "outbox_id", item.ID, "attempts", item.Attempts, "retry_in", delay, "error", sendErr)
+				err = o.repo.MarkRetry(item.ID, item.Attempts, sendErr.Error(), now.Add(delay), now)
+			}
+			if errors.Is(err, repo.ErrClaimLost) {
+				slog.Warn("通知的认领已过期，发送结果由重新认领的 Worker 记录", "outbox_id", item.ID, "attempts", item.Attempts)
+				continue
+			}
+			if err != nil {
+				slog.Error("更新通知发件箱状态失败", "outbox_id", item.ID, "error", err)
+				o.release(items[i+1:])
+				return
+			}
+		}
+	}
+}
+
+// release 放弃认领尚未发送的消息，让它们立即可以被重新认领，而不必等到认领过期。
+func (o *Outbox) release(items []models.NotificationOutbox) {
+	now := time.Now().UTC()
+	for _, item := range items {
+		if err := o.repo.Release(item.ID, item.Attempts, now); err != nil && !errors.Is(err, repo.ErrClaimLost) {
+			slog.Warn("放弃认领通知失败，将在认领过期后重新发送", "outbox_id", item.ID, "error", err)
+		}
+	}
+}
+
+// send 通过 sender 发送一条消息，并把这次发送记录为入队时所在链路的子 Span。
+// Span 的开始时间与入队时间之差就是消息在发件箱中等待的时间。
+func (o *Outbox) send(ctx context.Context, item models.NotificationOutbox, attempts int) error {
+	ctx, span := tracing.Tracer().Start(tracing.ContextWithTraceParent(ctx, item.TraceParent), "notify.deliver",
+		trace.WithAttributes(
+			attribute.Int("outbox.id", int(item.ID)),
+			attribute.Int("outbox.attempt", attempts),
+			attribute.Float64("outbox.queued_seconds", time.Since(item.CreatedAt).Seconds()),
+		))
+	defer span.End()
+
+	err := o.sender.Notify(ctx, Message{TodoID: item.TodoID, Title: item.Title, Body: item.Body})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// backoff 计算第 attempts 次失败后的等待时间：BaseDelay * 2^(attempts-1)，不超过 MaxDelay。
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.opts.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= o.opts.MaxDelay {
+			return o.opts.MaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,167 @@
+// Package reload 负责在不重启服务的情况下重新加载配置。
+// 配置文件变化、收到 SIGHUP 或调用 POST /admin/reload 时重新读取全部配置，
+// 其中可以在运行时生效的配置项（CORS 来源、Webhook Secret、推送目标、日志级别）立即替换，
+// 其余配置项的变化只记录为“需要重启”，正在运行的服务继续使用原来的值。
+package reload
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"backend/internal/config"
+)
+
+// 触发重新加载的方式，对应 Status.Trigger。
+const (
+	TriggerStartup = "startup"
+	TriggerFile    = "file"
+	TriggerSignal  = "signal"
+	TriggerAPI     = "api"
+)
+
+// Status 是最近一次重新加载的结果。
+type Status struct {
+	Trigger string    `json:"trigger"`
+	At      time.Time `json:"at"`
+	Success bool      `json:"success"`
+
+	// Error 是重新加载失败的原因。失败时不会替换任何配置项。
+	Error string `json:"error,omitempty"`
+
+	// Reloaded 是本次已经生效的配置项（配置文件中的键）。
+	Reloaded []string `json:"reloaded"`
+
+	// RestartRequired 是与正在使用的值不同、但需要重启才能生效的配置项。
+	// 只要没有重启，之后每次重新加载都会继续报告这些配置项。
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// Manager 持有正在使用的配置，并在重新加载时把变化分发给各个组件。
+type Manager struct {
+	load func() (config.Config, error)
+
+	mu       sync.Mutex // 保证同一时间只有一次重新加载
+	current  config.Config
+	appliers []func(config.Config)
+
+	status atomic.Pointer[Status]
+}
+
+// NewManager 创建 Manager。cfg 是启动时加载的配置，load 用于重新读取配置（配置文件、环境变量与命令行参数）。
+func NewManager(cfg config.Config, load func() (config.Config, error)) *Manager {
+	m := &Manager{load: load, current: cfg}
+	m.status.Store(&Status{Trigger: TriggerStartup, At: time.Now().UTC(), Success: true, Reloaded: []string{}, RestartRequired: []string{}})
+	return m
+}
+
+// OnReload 注册一个在配置重新加载后调用的函数，参数是替换了可重新加载配置项之后的完整配置。
+// 各组件在这里原子地替换自己持有的配置，例如 WebhookHandler.SetSecrets。
+// 应在启动服务之前注册。
+func (m *Manager) OnReload(apply func(config.Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.appliers = append(m.appliers, apply)
+}
+
+// Status 返回最近一次重新加载的结果，尚未重新加载过时返回启动时的状态。
+func (m *Manager) Status() Status {
+	return *m.status.Load()
+}
+
+// Reload 重新读取配置并替换可重新加载的配置项。
+// 新配置无效（例如配置文件写了一半）时保留原来的配置，并在返回的 Status 中记录原因。
+func (m *Manager) Reload(trigger string) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := Status{Trigger: trigger, At: time.Now().UTC(), Reloaded: []string{}, RestartRequired: []string{}}
+	next, err := m.load()
+	if err != nil {
+		status.Error = err.Error()
+		m.status.Store(&status)
+		slog.Error("重新加载配置失败，继续使用原配置", "trigger", trigger, "error", err)
+		return status
+	}
+
+	reloaded, restartRequired := m.current.Diff(next)
+	status.Success = true
+	status.Reloaded = append(status.Reloaded, reloaded...)
+	status.RestartRequired = append(status.RestartRequired, restartRequired...)
+
+	if len(reloaded) > 0 {
+		m.current.ApplyReloadable(next)
+		for _, apply := range m.appliers {
+			apply(m.current)
+		}
+	}
+	m.status.Store(&status)
+
+	slog.Info("配置已重新加载", "trigger", trigger, "reloaded", reloaded)
+	if len(restartRequired) > 0 {
+		slog.Warn("部分配置项的变化需要重启服务才能生效", "settings", restartRequired)
+	}
+	return status
+}
+
+// NotifySignal 开始接收 SIGHUP，返回交给 WatchSignal 的通道。
+// 进程默认收到 SIGHUP 会直接退出，因此应在 main 开始时同步调用，
+// 启动期间（连接数据库、执行迁移）收到的 SIGHUP 会保留在通道中，WatchSignal 启动后再重新加载。
+func NotifySignal() chan os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	return signals
+}
+
+// WatchSignal 在 signals（由 NotifySignal 创建）收到 SIGHUP 时重新加载配置，直到 ctx 取消。
+func (m *Manager) WatchSignal(ctx context.Context, signals chan os.Signal) {
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			m.Reload(TriggerSignal)
+		}
+	}
+}
+
+// WatchFile 定期检查配置文件，发生变化时重新加载配置，直到 ctx 取消。
+// 与证书一样通过修改时间与大小判断变化，不依赖文件系统通知，
+// 同样适用于 Kubernetes ConfigMap 这类通过替换符号链接更新的挂载方式。
+func (m *Manager) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	version, _ := fileVersion(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 文件暂时不存在（例如编辑器先删除再写入）时等下次检查
+			current, err := fileVersion(path)
+			if err != nil || current == version {
+				continue
+			}
+			version = current
+			m.Reload(TriggerFile)
+		}
+	}
+}
+
+// fileVersion 返回代表文件当前内容版本的字符串。
+func fileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size()), nil
+}
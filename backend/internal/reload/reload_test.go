@@ -0,0 +1,131 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/config"
+)
+
+// fakeLoader 返回最近一次通过 set 设置的配置与错误。
+type fakeLoader struct {
+	mu   sync.Mutex
+	next config.Config
+	err  error
+}
+
+func (l *fakeLoader) set(cfg config.Config, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.next, l.err = cfg, err
+}
+
+func (l *fakeLoader) load() (config.Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next, l.err
+}
+
+func TestReload(t *testing.T) {
+	initial := config.Config{BindAddr: ":8080", LogLevel: slog.LevelInfo, CORSAllowedOrigins: []string{"https://a.example.com"}}
+	loader := &fakeLoader{next: initial}
+	m := NewManager(initial, loader.load)
+
+	var applied []config.Config
+	m.OnReload(func(cfg config.Config) { applied = append(applied, cfg) })
+
+	if status := m.Status(); status.Trigger != TriggerStartup || !status.Success {
+		t.Fatalf("initial Status = %+v", status)
+	}
+
+	// 没有变化时不调用 applier
+	if status := m.Reload(TriggerAPI); !status.Success || len(status.Reloaded) != 0 || len(applied) != 0 {
+		t.Fatalf("Reload without changes = %+v, applied %d", status, len(applied))
+	}
+
+	// 可重新加载的配置项立即生效，其余配置项只报告需要重启
+	next := initial
+	next.LogLevel = slog.LevelDebug
+	next.CORSAllowedOrigins = []string{"https://b.example.com"}
+	next.BindAddr = ":9090"
+	loader.set(next, nil)
+	status := m.Reload(TriggerSignal)
+	if !status.Success || status.Trigger != TriggerSignal ||
+		strings.Join(status.Reloaded, ",") != "log.level,cors.allowed_origins" || strings.Join(status.RestartRequired, ",") != "server.bind_addr" {
+		t.Fatalf("Reload = %+v", status)
+	}
+	if len(applied) != 1 || applied[0].LogLevel != slog.LevelDebug || applied[0].CORSAllowedOrigins[0] != "https://b.example.com" || applied[0].BindAddr != ":8080" {
+		t.Fatalf("applied = %+v, want the reloadable settings on top of the running configuration", applied)
+	}
+	if got := m.Status(); got.Trigger != TriggerSignal || got.At != status.At {
+		t.Fatalf("Status = %+v, want the last reload", got)
+	}
+
+	// 没有重启之前，之后的重新加载继续报告需要重启的配置项
+	if status := m.Reload(TriggerAPI); len(status.Reloaded) != 0 || strings.Join(status.RestartRequired, ",") != "server.bind_addr" || len(applied) != 1 {
+		t.Fatalf("second Reload = %+v", status)
+	}
+
+	// 加载失败时保留原来的配置
+	loader.set(config.Config{}, errors.New("配置文件无效"))
+	if status := m.Reload(TriggerFile); status.Success || status.Error != "配置文件无效" || len(applied) != 1 {
+		t.Fatalf("failed Reload = %+v", status)
+	}
+	loader.set(next, nil)
+	if status := m.Reload(TriggerAPI); !status.Success || len(status.Reloaded) != 0 {
+		t.Fatalf("Reload after failure = %+v, want the previous configuration to be kept", status)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("log:\n  level: info\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	initial := config.Config{LogLevel: slog.LevelInfo}
+	next := config.Config{LogLevel: slog.LevelDebug}
+	reloaded := make(chan config.Config, 1)
+	m := NewManager(initial, func() (config.Config, error) { return next, nil })
+	m.OnReload(func(cfg config.Config) { reloaded <- cfg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.WatchFile(ctx, path, 10*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// 文件不变时不重新加载
+	select {
+	case <-reloaded:
+		t.Fatal("WatchFile reloaded an unchanged file")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("log:\n  level: debug\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case cfg := <-reloaded:
+		if cfg.LogLevel != slog.LevelDebug {
+			t.Fatalf("reloaded LogLevel = %v, want debug", cfg.LogLevel)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WatchFile did not reload after the file changed")
+	}
+	if status := m.Status(); status.Trigger != TriggerFile || !status.Success {
+		t.Fatalf("Status = %+v, want a successful file reload", status)
+	}
+}
@@ -0,0 +1,116 @@
+// Package replay 提供 Webhook 重放缓存。
+//
+// Infisical 的签名只覆盖请求体，签名头中的 t= 不受签名保护，可以被随意修改，
+// 因此请求是否过期以载荷中受签名保护的 timestamp 为准，时间戳校验只能把重放限制在时间窗口内。
+// 重放缓存记录已经处理过的签名摘要，并一直保留到载荷时间戳离开窗口为止，
+// 同一个请求即使被截获，也只会被接受一次。
+package replay
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"backend/internal/repo"
+)
+
+// Cache 记录已经处理过的签名摘要。
+type Cache interface {
+	// Remember 记录签名摘要，保留到 expiresAt。
+	// expiresAt 应不早于载荷时间戳离开窗口的时间，否则同一个请求可以在摘要过期后再次通过校验。
+	// 摘要已经出现过且尚未过期时返回 false，表示这是一次重放。
+	Remember(digest string, now, expiresAt time.Time) (bool, error)
+
+	// Forget 删除签名摘要。请求处理失败时调用，允许发送方用同一个请求重试。
+	Forget(digest string) error
+}
+
+// MemoryCache 是保存在进程内存中的重放缓存。
+type MemoryCache struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // 摘要 -> 过期时间
+	lastSweep time.Time
+}
+
+// NewMemoryCache 创建内存重放缓存，每隔 window 清理一次过期的摘要。
+func NewMemoryCache(window time.Duration) *MemoryCache {
+	return &MemoryCache{window: window, seen: make(map[string]time.Time)}
+}
+
+// Remember 实现 Cache 接口。
+func (c *MemoryCache) Remember(digest string, now, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(now)
+	if expiresAt, ok := c.seen[digest]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	c.seen[digest] = expiresAt
+	return true, nil
+}
+
+// Forget 实现 Cache 接口。
+func (c *MemoryCache) Forget(digest string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.seen, digest)
+	return nil
+}
+
+// sweep 每个窗口期清理一次过期的摘要，避免内存无限增长。调用方需持有锁。
+func (c *MemoryCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < c.window {
+		return
+	}
+	for digest, expiresAt := range c.seen {
+		if !now.Before(expiresAt) {
+			delete(c.seen, digest)
+		}
+	}
+	c.lastSweep = now
+}
+
+// DBCache 是保存在数据库中的重放缓存。
+type DBCache struct {
+	repo   *repo.NonceRepository
+	window time.Duration
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewDBCache 创建数据库重放缓存，每隔 window 清理一次过期的摘要。
+func NewDBCache(repo *repo.NonceRepository, window time.Duration) *DBCache {
+	return &DBCache{repo: repo, window: window}
+}
+
+// Remember 实现 Cache 接口。
+func (c *DBCache) Remember(digest string, now, expiresAt time.Time) (bool, error) {
+	c.sweep(now)
+	return c.repo.Remember(digest, now, expiresAt)
+}
+
+// Forget 实现 Cache 接口。
+func (c *DBCache) Forget(digest string) error {
+	return c.repo.Forget(digest)
+}
+
+// sweep 每个窗口期删除一次过期的摘要。
+// 过期记录不影响判断结果，清理失败只记录日志。
+func (c *DBCache) sweep(now time.Time) {
+	c.mu.Lock()
+	if now.Sub(c.lastSweep) < c.window {
+		c.mu.Unlock()
+		return
+	}
+	c.lastSweep = now
+	c.mu.Unlock()
+
+	if _, err := c.repo.DeleteExpired(now); err != nil {
+		slog.Warn("清理过期的 Webhook 签名摘要失败", "error", err)
+	}
+}
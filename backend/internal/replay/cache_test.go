@@ -0,0 +1,26 @@
+package replay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheKeepsDigestUntilExpiry(t *testing.T) {
+	window := time.Minute
+	cache := NewMemoryCache(window)
+	now := time.Now()
+
+	// 载荷时间戳在未来时，摘要要保留到它离开窗口为止，而不是从收到请求起保留一个窗口期
+	expiresAt := now.Add(3 * window)
+	if ok, _ := cache.Remember("digest", now, expiresAt); !ok {
+		t.Fatal("first Remember = false, want true")
+	}
+	for _, at := range []time.Time{now.Add(window), now.Add(2 * window), expiresAt.Add(-time.Second)} {
+		if ok, _ := cache.Remember("digest", at, at.Add(window)); ok {
+			t.Fatalf("Remember at +%v = true, want false until %v", at.Sub(now), expiresAt.Sub(now))
+		}
+	}
+	if ok, _ := cache.Remember("digest", expiresAt, expiresAt.Add(window)); !ok {
+		t.Fatal("Remember after expiry = false, want true")
+	}
+}
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NonceRepository 封装 Webhook 签名摘要（重放缓存）的数据库操作。
+type NonceRepository struct {
+	db *gorm.DB
+}
+
+// NewNonceRepository 创建并返回一个新的 NonceRepository 实例。
+func NewNonceRepository(db *gorm.DB) *NonceRepository {
+	return &NonceRepository{db: db}
+}
+
+// Remember 记录签名摘要，有效期到 expiresAt。
+// 摘要已存在且尚未过期时返回 false；已过期的旧记录会被覆盖。
+// 判断与写入在一条 INSERT ... ON CONFLICT DO UPDATE ... WHERE 语句中完成，
+// 多个实例共用同一个数据库时，同一个摘要也只会有一个请求返回 true。
+func (r *NonceRepository) Remember(digest string, now, expiresAt time.Time) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "digest"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "webhook_nonces.expires_at <= ?", Vars: []interface{}{now}},
+		}},
+	}).Create(&models.WebhookNonce{Digest: digest, ExpiresAt: expiresAt})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Forget 删除签名摘要。
+func (r *NonceRepository) Forget(digest string) error {
+	return r.db.Where("digest = ?", digest).Delete(&models.WebhookNonce{}).Error
+}
+
+// DeleteExpired 删除所有已过期的签名摘要，返回删除的数量。
+func (r *NonceRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.WebhookNonce{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"backend/internal/testdb"
+
+	"gorm.io/gorm"
+)
+
+func TestNonceRemember(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		nonces := NewNonceRepository(database)
+		now := time.Now().UTC()
+
+		steps := []struct {
+			now, expiresAt time.Time
+			want           bool
+		}{
+			{now: now, expiresAt: now.Add(time.Minute), want: true},
+			// 尚未过期的摘要不能再次记录
+			{now: now.Add(30 * time.Second), expiresAt: now.Add(2 * time.Minute), want: false},
+			// 过期后 ON CONFLICT DO UPDATE ... WHERE 覆盖旧记录
+			{now: now.Add(time.Minute), expiresAt: now.Add(3 * time.Minute), want: true},
+			{now: now.Add(2 * time.Minute), expiresAt: now.Add(4 * time.Minute), want: false},
+		}
+		for i, step := range steps {
+			got, err := nonces.Remember("digest", step.now, step.expiresAt)
+			if err != nil {
+				t.Fatalf("step %d: Remember: %v", i, err)
+			}
+			if got != step.want {
+				t.Fatalf("step %d: Remember = %v, want %v", i, got, step.want)
+			}
+		}
+
+		if err := nonces.Forget("digest"); err != nil {
+			t.Fatalf("Forget: %v", err)
+		}
+		if ok, err := nonces.Remember("digest", now, now.Add(time.Minute)); err != nil || !ok {
+			t.Fatalf("Remember after Forget = %v, %v; want true", ok, err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotDead 表示消息不处于死信状态，不能重新入队。
+var ErrNotDead = errors.New("notification is not dead-lettered")
+
+// OutboxRepository 封装通知发件箱的数据库操作。
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository 创建并返回一个新的 OutboxRepository 实例。
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// WithContext 返回一个绑定了 ctx 的 Repository 副本，用法与 TodoRepository.WithContext 相同。
+func (r *OutboxRepository) WithContext(ctx context.Context) *OutboxRepository {
+	return &OutboxRepository{db: r.db.WithContext(ctx)}
+}
+
+// ErrClaimLost 表示消息的认领已经过期并被其他 Worker 重新认领，本次发送结果不再记录。
+var ErrClaimLost = errors.New("notification claim expired")
+
+// OutboxMessage 是写入发件箱的通知内容。
+type OutboxMessage struct {
+	Title string
+	Body  string
+
+	// TraceParent 是入队时所在链路的 W3C traceparent，可以为空。
+	TraceParent string
+}
+
+// newOutboxItem 构造一条 pending 状态、立即可发送的发件箱记录。
+func newOutboxItem(todoID uint, msg OutboxMessage, now time.Time) models.NotificationOutbox {
+	return models.NotificationOutbox{
+		TodoID:        todoID,
+		Title:         msg.Title,
+		Body:          msg.Body,
+		TraceParent:   msg.TraceParent,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// Enqueue 将一条通知写入发件箱，状态为 pending，立即可发送。
+// 需要与业务数据一起提交的通知应在同一个事务中写入，见 TodoRepository.UpsertFromWebhook。
+func (r *OutboxRepository) Enqueue(todoID uint, msg OutboxMessage, now time.Time) (models.NotificationOutbox, error) {
+	item := newOutboxItem(todoID, msg, now)
+	if err := r.db.Create(&item).Error; err != nil {
+		return models.NotificationOutbox{}, err
+	}
+	return item, nil
+}
+
+// ClaimDue 认领最多 limit 条已到发送时间的消息，返回认领到的消息，按 ID 排列。
+//
+// 认领在一条 UPDATE ... RETURNING 语句中完成：消息改为 sending 状态，Attempts 加一，
+// NextAttemptAt 改为 now + lease 作为认领的截止时间。多个实例共用同一个 PostgreSQL 时，
+// 子查询使用 FOR UPDATE SKIP LOCKED 跳过其他实例正在认领的行，外层再次检查状态，
+// 同一条消息只会被一个实例认领。SQLite 只有一个连接，语句天然是串行的。
+// 认领后没有在截止时间前记录发送结果（例如 Worker 崩溃）的消息会被重新认领。
+func (r *OutboxRepository) ClaimDue(now time.Time, lease time.Duration, limit int) ([]models.NotificationOutbox, error) {
+	due := func(db *gorm.DB) *gorm.DB {
+		return db.Where("status IN ? AND next_attempt_at <= ?", []string{models.OutboxStatusPending, models.OutboxStatusSending}, now)
+	}
+
+	candidates := due(r.db.Model(&models.NotificationOutbox{}).Select("id")).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit)
+	if r.db.Dialector.Name() == "postgres" {
+		candidates = candidates.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+	}
+
+	var items []models.NotificationOutbox
+	if err := due(r.db.Model(&items).Clauses(clause.Returning{}).Where("id IN (?)", candidates)).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusSending,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(lease),
+			"updated_at":      now,
+		}).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+// MarkDelivered 将消息标记为已发送。attempts 是认领时得到的 Attempts。
+func (r *OutboxRepository) MarkDelivered(id uint, attempts int, now time.Time) error {
+	return r.updateClaimed(id, attempts, map[string]interface{}{
+		"status":       models.OutboxStatusDelivered,
+		"last_error":   "",
+		"delivered_at": &now,
+		"updated_at":   now,
+	})
+}
+
+// MarkRetry 记录一次发送失败，并安排在 nextAttemptAt 重试。
+func (r *OutboxRepository) MarkRetry(id uint, attempts int, lastError string, nextAttemptAt, now time.Time) error {
+	return r.updateClaimed(id, attempts, map[string]interface{}{
+		"status":          models.OutboxStatusPending,
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+		"updated_at":      now,
+	})
+}
+
+// MarkDead 记录最后一次发送失败，并将消息移入死信状态。
+func (r *OutboxRepository) MarkDead(id uint, attempts int, lastError string, now time.Time) error {
+	return r.updateClaimed(id, attempts, map[string]interface{}{
+		"status":     models.OutboxStatusDead,
+		"last_error": lastError,
+		"updated_at": now,
+	})
+}
+
+// Release 放弃认领尚未发送的消息（例如服务正在关闭），消息恢复为 pending 并立即可发送，不计入发送次数。
+func (r *OutboxRepository) Release(id uint, attempts int, now time.Time) error {
+	return r.updateClaimed(id, attempts, map[string]interface{}{
+		"status":          models.OutboxStatusPending,
+		"attempts":        attempts - 1,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	})
+}
+
+// updateClaimed 更新仍由本次认领持有的消息。
+// 认领已过期并被重新认领时 Attempts 已经变化，不更新任何记录并返回 ErrClaimLost。
+func (r *OutboxRepository) updateClaimed(id uint, attempts int, updates map[string]interface{}) error {
+	result := r.db.Model(&models.NotificationOutbox{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, models.OutboxStatusSending, attempts).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrClaimLost
+	}
+	return nil
+}
+
+// ListDead 返回所有死信消息，最近失败的在前面。
+func (r *OutboxRepository) ListDead() ([]models.NotificationOutbox, error) {
+	var items []models.NotificationOutbox
+	if err := r.db.Where("status = ?", models.OutboxStatusDead).Order("updated_at desc, id desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Requeue 将死信消息重新放回发送队列，尝试次数清零并立即可发送。
+// 消息不存在时返回 gorm.ErrRecordNotFound，不处于死信状态时返回 ErrNotDead。
+func (r *OutboxRepository) Requeue(id uint, now time.Time) (models.NotificationOutbox, error) {
+	var item models.NotificationOutbox
+	if err := r.db.First(&item, id).Error; err != nil {
+		return models.NotificationOutbox{}, err
+	}
+	if item.Status != models.OutboxStatusDead {
+		return models.NotificationOutbox{}, ErrNotDead
+	}
+
+	if err := r.db.Model(&item).Updates(map[string]interface{}{
+		"status":          models.OutboxStatusPending,
+		"attempts":        0,
+		"next_attempt_at": now,
+		"updated_at":      now,
+	}).Error; err != nil {
+		return models.NotificationOutbox{}, err
+	}
+	item.Status = models.OutboxStatusPending
+	item.Attempts = 0
+	item.NextAttemptAt = now
+	item.UpdatedAt = now
+	return item, nil
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/testdb"
+
+	"gorm.io/gorm"
+)
+
+func TestOutboxClaimDue(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		outbox := NewOutboxRepository(database)
+		now := time.Now().UTC().Truncate(time.Second)
+		lease := time.Minute
+
+		for i := 0; i < 3; i++ {
+			if _, err := outbox.Enqueue(0, OutboxMessage{Title: "t", Body: "b"}, now); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+		}
+
+		claimed, err := outbox.ClaimDue(now, lease, 10)
+		if err != nil {
+			t.Fatalf("ClaimDue: %v", err)
+		}
+		if len(claimed) != 3 {
+			t.Fatalf("claimed %d messages, want 3", len(claimed))
+		}
+		for _, item := range claimed {
+			if item.Status != models.OutboxStatusSending || item.Attempts != 1 {
+				t.Fatalf("claimed message = status %q attempts %d, want sending / 1", item.Status, item.Attempts)
+			}
+		}
+
+		// 认领期间不会被再次认领
+		again, err := outbox.ClaimDue(now.Add(lease/2), lease, 10)
+		if err != nil {
+			t.Fatalf("ClaimDue: %v", err)
+		}
+		if len(again) != 0 {
+			t.Fatalf("claimed %d messages during lease, want 0", len(again))
+		}
+
+		if err := outbox.MarkDelivered(claimed[0].ID, claimed[0].Attempts, now); err != nil {
+			t.Fatalf("MarkDelivered: %v", err)
+		}
+		if err := outbox.Release(claimed[1].ID, claimed[1].Attempts, now); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+
+		// 认领过期后，没有记录结果的消息被重新认领，旧的认领不能再覆盖状态
+		expired := now.Add(lease + time.Second)
+		reclaimed, err := outbox.ClaimDue(expired, lease, 10)
+		if err != nil {
+			t.Fatalf("ClaimDue: %v", err)
+		}
+		if len(reclaimed) != 2 {
+			t.Fatalf("reclaimed %d messages, want 2", len(reclaimed))
+		}
+		attempts := map[uint]int{}
+		for _, item := range reclaimed {
+			attempts[item.ID] = item.Attempts
+		}
+		if attempts[claimed[1].ID] != 1 {
+			t.Errorf("released message attempts = %d, want 1", attempts[claimed[1].ID])
+		}
+		if attempts[claimed[2].ID] != 2 {
+			t.Errorf("expired message attempts = %d, want 2", attempts[claimed[2].ID])
+		}
+		if err := outbox.MarkRetry(claimed[2].ID, claimed[2].Attempts, "boom", expired, expired); !errors.Is(err, ErrClaimLost) {
+			t.Errorf("MarkRetry with expired claim = %v, want ErrClaimLost", err)
+		}
+	})
+}
+
+func TestOutboxClaimDueConcurrent(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		outbox := NewOutboxRepository(database)
+		now := time.Now().UTC()
+
+		const messages = 40
+		for i := 0; i < messages; i++ {
+			if _, err := outbox.Enqueue(0, OutboxMessage{Title: "t", Body: "b"}, now); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+		}
+
+		// 多个 Worker 同时认领，每条消息只能被认领一次
+		var (
+			mu      sync.Mutex
+			claimed = map[uint]int{}
+			wg      sync.WaitGroup
+		)
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					items, err := outbox.ClaimDue(now, time.Minute, 3)
+					if err != nil {
+						t.Errorf("ClaimDue: %v", err)
+						return
+					}
+					if len(items) == 0 {
+						return
+					}
+					mu.Lock()
+					for _, item := range items {
+						claimed[item.ID]++
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(claimed) != messages {
+			t.Fatalf("claimed %d distinct messages, want %d", len(claimed), messages)
+		}
+		for id, count := range claimed {
+			if count != 1 {
+				t.Errorf("message %d claimed %d times", id, count)
+			}
+		}
+	})
+}
@@ -3,12 +3,20 @@
 package repo
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
+	"backend/internal/metrics"
 	"backend/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TodoRepository 结构体包含一个 GORM 数据库实例。
@@ -23,25 +31,277 @@ func NewTodoRepository(db *gorm.DB) *TodoRepository {
 	return &TodoRepository{db: db}
 }
 
-// List 返回所有待办事项，按 ID 倒序排列（最新的在前面）。
-func (r *TodoRepository) List() ([]models.TodoItem, error) {
+// WithContext 返回一个绑定了 ctx 的 Repository 副本。
+// 之后的数据库操作会在 ctx 取消时中止，链路追踪也会把每条 SQL 语句记录为 ctx 中当前 Span 的子节点。
+func (r *TodoRepository) WithContext(ctx context.Context) *TodoRepository {
+	return &TodoRepository{db: r.db.WithContext(ctx)}
+}
+
+// TodoFields 描述创建或更新待办事项时携带的属性。
+// ProjectID + Environment + SecretPath 共同决定一条待办事项的身份，
+// 其余字段仅用于展示，会在每次 Webhook 到达时刷新。
+type TodoFields struct {
+	ProjectID    string
+	ProjectName  string
+	Environment  string
+	SecretPath   string
+	SecretName   string
+	ReminderNote string
+
+	// Source 是 Webhook 来源的 Slug，手动创建或来自默认 Webhook 地址时为空。
+	Source string
+}
+
+// WebhookEvent 描述一次被接受的 Webhook 投递，用于写入事件时间线。
+type WebhookEvent struct {
+	EventType          string
+	InfisicalTimestamp int64
+	SourceIP           string
+	BodyHash           string
+}
+
+// newTodoItem 根据 TodoFields 构造一个未完成的待办事项。
+func newTodoItem(fields TodoFields, now time.Time) models.TodoItem {
+	return models.TodoItem{
+		ProjectID:    fields.ProjectID,
+		ProjectName:  fields.ProjectName,
+		Environment:  fields.Environment,
+		SecretPath:   fields.SecretPath,
+		SecretName:   fields.SecretName,
+		ReminderNote: fields.ReminderNote,
+		IsCompleted:  false,
+		CreatedAt:    now,
+		OpenedAt:     now,
+		Source:       fields.Source,
+	}
+}
+
+// TodoSort 是列表查询支持的排序字段（对应数据库列名）。
+type TodoSort string
+
+const (
+	SortByID         TodoSort = "id"
+	SortByCreatedAt  TodoSort = "created_at"
+	SortBySecretPath TodoSort = "secret_path"
+)
+
+// ErrInvalidCursor 表示分页游标无法解析，或与本次查询的排序方式不匹配。
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TodoFilter 描述列表查询的过滤、排序与分页条件。
+// 零值表示不过滤、按 ID 倒序、返回全部记录，与旧版 List 的行为一致。
+type TodoFilter struct {
+	// Completed 为 nil 时不按完成状态过滤。
+	Completed *bool
+
+	// PathPrefix 按密钥路径前缀匹配。
+	PathPrefix string
+
+	// Search 在路径、密钥名、项目名、环境与提醒备注中做不区分大小写的子串匹配。
+	Search string
+
+	// ProjectID 与 Environment 精确匹配，为空时不过滤。
+	ProjectID   string
+	Environment string
+
+	// Source 按 Webhook 来源精确匹配，为 nil 时不过滤；指向空字符串时只返回没有来源的记录。
+	Source *string
+
+	// 时间范围均为左闭右开区间 [From, To)，为 nil 时不限制。
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	CompletedFrom *time.Time
+	CompletedTo   *time.Time
+
+	// Sort 为空时按 ID 排序。Desc 为 true 时倒序。
+	Sort TodoSort
+	Desc bool
+
+	// Limit 为 0 时返回全部记录（不分页）。
+	Limit int
+
+	// Cursor 是上一页返回的 NextCursor，为空时从第一页开始。
+	Cursor string
+}
+
+// TodoPage 是一次列表查询的结果。
+type TodoPage struct {
+	Items []models.TodoItem
+
+	// Total 是满足过滤条件的记录总数（不受分页影响）。
+	Total int64
+
+	// NextCursor 用于获取下一页，没有更多数据时为空字符串。
+	NextCursor string
+}
+
+// todoCursor 是分页游标的内部结构，序列化后以 base64url 编码交给客户端。
+// 游标记录了上一页最后一条记录的排序值与 ID（keyset 分页），
+// 因此翻页期间插入或删除记录不会导致数据重复或遗漏。
+type todoCursor struct {
+	Sort  TodoSort `json:"s"`
+	Desc  bool     `json:"d"`
+	Value string   `json:"v,omitempty"`
+	ID    uint     `json:"id"`
+}
+
+// List 按过滤条件查询待办事项。
+// 分页采用 (排序字段, id) 组合的 keyset 方式，id 作为排序值相同时的次序保证。
+func (r *TodoRepository) List(filter TodoFilter) (TodoPage, error) {
+	sort := filter.Sort
+	if sort == "" {
+		sort = SortByID
+	}
+
+	query := applyTodoFilter(r.db.Model(&models.TodoItem{}), filter)
+
+	// 先统计总数，再叠加游标条件与排序。
+	// Session 复制一份查询条件，避免 Count 修改后续查询使用的语句。
+	var page TodoPage
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return TodoPage{}, err
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeTodoCursor(filter.Cursor)
+		if err != nil || cursor.Sort != sort || cursor.Desc != filter.Desc {
+			return TodoPage{}, ErrInvalidCursor
+		}
+		query, err = applyTodoCursor(query, cursor)
+		if err != nil {
+			return TodoPage{}, err
+		}
+	}
+
+	direction := "asc"
+	if filter.Desc {
+		direction = "desc"
+	}
+	if sort != SortByID {
+		query = query.Order(string(sort) + " " + direction)
+	}
+	query = query.Order("id " + direction)
+
+	// 多取一条用于判断是否还有下一页
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit + 1)
+	}
+
 	var items []models.TodoItem
-	// Find 方法会自动生成 SELECT * FROM todo_items 查询。
-	// Order("id desc") 添加 ORDER BY id DESC 子句。
-	// 结果被扫描到 items 切片中。
-	if err := r.db.Order("id desc").Find(&items).Error; err != nil {
-		return nil, err
+	if err := query.Find(&items).Error; err != nil {
+		return TodoPage{}, err
+	}
+
+	if filter.Limit > 0 && len(items) > filter.Limit {
+		items = items[:filter.Limit]
+		page.NextCursor = encodeTodoCursor(sort, filter.Desc, items[len(items)-1])
 	}
-	return items, nil
+	page.Items = items
+	return page, nil
 }
 
-// Create 创建一个新的待办事项。
-func (r *TodoRepository) Create(secretPath string, now time.Time) (models.TodoItem, error) {
-	item := models.TodoItem{
-		SecretPath:  secretPath,
-		IsCompleted: false,
-		CreatedAt:   now,
+// todoSearchColumns 是关键字搜索覆盖的列。
+var todoSearchColumns = []string{"secret_path", "secret_name", "project_name", "environment", "reminder_note"}
+
+// applyTodoFilter 将过滤条件转换为 WHERE 子句。
+func applyTodoFilter(query *gorm.DB, filter TodoFilter) *gorm.DB {
+	if filter.Completed != nil {
+		query = query.Where("is_completed = ?", *filter.Completed)
+	}
+	if filter.PathPrefix != "" {
+		query = query.Where(`secret_path LIKE ? ESCAPE '\'`, escapeLike(filter.PathPrefix)+"%")
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		conditions := make([]string, 0, len(todoSearchColumns))
+		args := make([]interface{}, 0, len(todoSearchColumns))
+		for _, column := range todoSearchColumns {
+			conditions = append(conditions, "LOWER("+column+`) LIKE ? ESCAPE '\'`)
+			args = append(args, pattern)
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	if filter.ProjectID != "" {
+		query = query.Where("project_id = ?", filter.ProjectID)
+	}
+	if filter.Environment != "" {
+		query = query.Where("environment = ?", filter.Environment)
+	}
+	if filter.Source != nil {
+		query = query.Where("source = ?", *filter.Source)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", filter.CreatedFrom.UTC())
 	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", filter.CreatedTo.UTC())
+	}
+	if filter.CompletedFrom != nil {
+		query = query.Where("completed_at >= ?", filter.CompletedFrom.UTC())
+	}
+	if filter.CompletedTo != nil {
+		query = query.Where("completed_at < ?", filter.CompletedTo.UTC())
+	}
+	return query
+}
+
+// applyTodoCursor 添加 keyset 分页条件：只返回排在游标记录之后的数据。
+func applyTodoCursor(query *gorm.DB, cursor todoCursor) (*gorm.DB, error) {
+	op := ">"
+	if cursor.Desc {
+		op = "<"
+	}
+
+	switch cursor.Sort {
+	case SortByID:
+		return query.Where("id "+op+" ?", cursor.ID), nil
+	case SortByCreatedAt:
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		value = value.UTC()
+		return query.Where("(created_at "+op+" ? OR (created_at = ? AND id "+op+" ?))", value, value, cursor.ID), nil
+	case SortBySecretPath:
+		return query.Where("(secret_path "+op+" ? OR (secret_path = ? AND id "+op+" ?))", cursor.Value, cursor.Value, cursor.ID), nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
+
+// encodeTodoCursor 根据当前页最后一条记录生成下一页的游标。
+func encodeTodoCursor(sort TodoSort, desc bool, last models.TodoItem) string {
+	cursor := todoCursor{Sort: sort, Desc: desc, ID: last.ID}
+	switch sort {
+	case SortByCreatedAt:
+		cursor.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortBySecretPath:
+		cursor.Value = last.SecretPath
+	}
+	// todoCursor 只包含基本类型，序列化不会失败
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeTodoCursor 解析客户端传回的游标。
+func decodeTodoCursor(value string) (todoCursor, error) {
+	var cursor todoCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(raw, &cursor)
+	return cursor, err
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，使用户输入按字面量匹配。
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// Create 创建一个新的待办事项。
+func (r *TodoRepository) Create(fields TodoFields, now time.Time) (models.TodoItem, error) {
+	item := newTodoItem(fields, now)
 	// Create 方法将结构体插入数据库。
 	// 如果插入失败（例如违反唯一约束），会返回 error。
 	if err := r.db.Create(&item).Error; err != nil {
@@ -59,10 +319,34 @@ func (r *TodoRepository) GetByID(id uint) (models.TodoItem, error) {
 	return item, nil
 }
 
+// Complete 将待办事项标记为已完成，并记录完成时间、操作者与备注。
+// 该操作是幂等的：对已完成的记录重复调用不会覆盖原有的完成信息，
+// 因此客户端在超时后重试也是安全的。
+func (r *TodoRepository) Complete(id uint, now time.Time, completedBy, note string) (models.TodoItem, error) {
+	return r.setCompleted(id, true, map[string]interface{}{
+		"is_completed":    true,
+		"completed_at":    &now, // 设置完成时间
+		"completed_by":    completedBy,
+		"completion_note": note,
+	})
+}
+
+// Reopen 将待办事项重置为未完成，清空完成时间、操作者与备注，并把重新打开的时间记为 now。
+// 与 Complete 一样是幂等的：对未完成的记录重复调用不会改变 opened_at。
+func (r *TodoRepository) Reopen(id uint, now time.Time) (models.TodoItem, error) {
+	return r.setCompleted(id, false, map[string]interface{}{
+		"is_completed":    false,
+		"completed_at":    nil, // 清空完成时间
+		"completed_by":    "",
+		"completion_note": "",
+		"opened_at":       now,
+	})
+}
+
 // ToggleComplete 切换待办事项的完成状态。
-// 如果当前为未完成，则标记为已完成并设置完成时间；
-// 如果当前为已完成，则重置为未完成并清空完成时间。
-func (r *TodoRepository) ToggleComplete(id uint, now time.Time) (models.TodoItem, error) {
+// 如果当前为未完成，则调用 Complete；如果当前为已完成，则调用 Reopen。
+// 仅为兼容旧客户端保留，新代码应直接使用 Complete / Reopen。
+func (r *TodoRepository) ToggleComplete(id uint, now time.Time, completedBy, note string) (models.TodoItem, error) {
 	var item models.TodoItem
 	// First 方法查找第一条匹配记录，如果没找到会返回 gorm.ErrRecordNotFound。
 	if err := r.db.First(&item, id).Error; err != nil {
@@ -71,79 +355,201 @@ func (r *TodoRepository) ToggleComplete(id uint, now time.Time) (models.TodoItem
 
 	// 根据当前状态决定切换方向
 	if item.IsCompleted {
-		// 当前已完成 → 切换为未完成
-		if err := r.db.Model(&item).Updates(map[string]interface{}{
-			"is_completed": false,
-			"completed_at": nil, // 清空完成时间
-		}).Error; err != nil {
-			return models.TodoItem{}, err
-		}
-		item.IsCompleted = false
-		item.CompletedAt = nil
-	} else {
-		// 当前未完成 → 切换为已完成
-		if err := r.db.Model(&item).Updates(map[string]interface{}{
-			"is_completed": true,
-			"completed_at": &now, // 设置完成时间
-		}).Error; err != nil {
-			return models.TodoItem{}, err
+		return r.Reopen(id, now)
+	}
+	return r.Complete(id, now, completedBy, note)
+}
+
+// setCompleted 在事务中把记录更新为目标状态，并返回更新后的记录。
+// UPDATE 语句带上 is_completed 的当前值作为条件，只有状态确实需要变化时才会写入，
+// 已处于目标状态的记录保持原样。
+func (r *TodoRepository) setCompleted(id uint, completed bool, updates map[string]interface{}) (models.TodoItem, error) {
+	var item models.TodoItem
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TodoItem{}).
+			Where("id = ? AND is_completed = ?", id, !completed).
+			Updates(updates).Error; err != nil {
+			return err
 		}
-		item.IsCompleted = true
-		item.CompletedAt = &now
+		// 记录不存在时 First 返回 gorm.ErrRecordNotFound
+		return tx.First(&item, id).Error
+	})
+	if err != nil {
+		return models.TodoItem{}, err
 	}
+	return item, nil
+}
 
+// Delete 根据 ID 删除待办事项，并一并删除其事件时间线。
+// 返回被删除的记录，便于调用方广播删除事件。
+func (r *TodoRepository) Delete(id uint) (models.TodoItem, error) {
+	var item models.TodoItem
+	// Transaction 中返回 error 会自动回滚，返回 nil 则提交。
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 记录不存在时 First 返回 gorm.ErrRecordNotFound
+		if err := tx.First(&item, id).Error; err != nil {
+			return err
+		}
+		// Delete 方法生成 DELETE 语句。
+		result := tx.Delete(&models.TodoItem{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		// 通过 RowsAffected 检查是否真的删除了数据。
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("todo_id = ?", id).Delete(&models.TodoEvent{}).Error
+	})
+	if err != nil {
+		return models.TodoItem{}, err
+	}
 	return item, nil
 }
 
-// Delete 根据 ID 删除待办事项。
-func (r *TodoRepository) Delete(id uint) error {
-	// Delete 方法生成 DELETE 语句。
-	// 即使记录不存在，Delete 通常也不会报错。
-	result := r.db.Delete(&models.TodoItem{}, id)
-	if result.Error != nil {
-		return result.Error
+// ListEvents 返回指定待办事项的事件时间线，按接收时间倒序排列（最新的在前面）。
+// 如果待办事项不存在，返回 gorm.ErrRecordNotFound。
+func (r *TodoRepository) ListEvents(todoID uint) ([]models.TodoEvent, error) {
+	if err := r.db.Select("id").First(&models.TodoItem{}, todoID).Error; err != nil {
+		return nil, err
 	}
-	// 通过 RowsAffected 检查是否真的删除了数据。
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+
+	var events []models.TodoEvent
+	if err := r.db.Where("todo_id = ?", todoID).Order("received_at desc, id desc").Find(&events).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	return events, nil
 }
 
-// UpsertFromWebhook 处理 Webhook 事件：如果记录存在则重置状态，如果不存在则创建。
-// Upsert = Update + Insert
-func (r *TodoRepository) UpsertFromWebhook(secretPath string, now time.Time) (models.TodoItem, error) {
-	var item models.TodoItem
-	// 尝试根据 secret_path 查找记录
-	err := r.db.Where("secret_path = ?", secretPath).First(&item).Error
-
-	if err == nil {
-		// 1. 记录存在：重置为 "未完成" 状态。
-		// 这意味着 Infisical 端发生了变更，需要重新处理这个 Todo。
-		if err := r.db.Model(&item).Updates(map[string]interface{}{
-			"is_completed": false,
-			"completed_at": nil, // 将字段置为 NULL
-		}).Error; err != nil {
-			return models.TodoItem{}, err
-		}
-		item.IsCompleted = false
-		item.CompletedAt = nil
-		return item, nil
+// newInsertToken 生成 16 字节的随机值，写入新插入记录的 insert_token 列。
+func newInsertToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf) // crypto/rand.Read 不会返回错误
+	return hex.EncodeToString(buf)
+}
+
+// Stats 返回待办事项的汇总状态，供 /metrics 在每次抓取时读取。
+// “最早”按 opened_at 计算：已完成的待办事项被 Webhook 重置时 created_at 保持不变，
+// 按创建时间计算会把刚重置的记录算成已经等待了很久。
+func (r *TodoRepository) Stats() (metrics.TodoStats, error) {
+	var stats metrics.TodoStats
+	if err := r.db.Model(&models.TodoItem{}).Where("is_completed = ?", false).Count(&stats.Open).Error; err != nil {
+		return metrics.TodoStats{}, err
+	}
+	if err := r.db.Model(&models.TodoItem{}).Where("is_completed = ?", true).Count(&stats.Completed).Error; err != nil {
+		return metrics.TodoStats{}, err
+	}
+	if stats.Open == 0 {
+		return stats, nil
 	}
 
-	// 检查错误是否真的为 "记录未找到"
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return models.TodoItem{}, err // 发生了其他数据库错误
+	// 用 ORDER BY + LIMIT 而不是 MIN()：聚合函数的结果在 SQLite 中没有列类型，无法直接扫描为 time.Time
+	var oldest models.TodoItem
+	if err := r.db.Select("opened_at").Where("is_completed = ?", false).Order("opened_at asc").Take(&oldest).Error; err != nil {
+		// 两次查询之间最后一条未完成的待办事项被完成或删除，视为没有未完成的待办事项
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return stats, nil
+		}
+		return metrics.TodoStats{}, err
 	}
+	stats.OldestOpenAt = &oldest.OpenedAt
+	return stats, nil
+}
 
-	// 2. 记录不存在：创建新记录
-	item = models.TodoItem{
-		SecretPath:  secretPath,
-		IsCompleted: false,
-		CreatedAt:   now,
+// todoIdentityColumns 是唯一索引 idx_todo_identity 包含的列，用于 ON CONFLICT 子句。
+var todoIdentityColumns = []clause.Column{{Name: "project_id"}, {Name: "environment"}, {Name: "secret_path"}}
+
+// claimLegacyTodo 把旧版本遗留的记录归属到本次 Webhook 的项目与环境。
+//
+// 旧版本只按路径识别待办事项，升级后这些记录的 project_id 与 environment 为空，
+// 携带真实项目的 Webhook 到达时会按新的身份另建一条记录，导致同一个密钥出现两条待办事项。
+// 这里在插入前把同一路径、尚未归属的记录更新为本次的项目与环境，之后的 ON CONFLICT 会重置这条记录。
+// 新身份的记录已经存在时不再认领，避免违反唯一索引；
+// 多个项目使用同一路径时，遗留记录归属于最先到达的那一个。
+func claimLegacyTodo(tx *gorm.DB, fields TodoFields) error {
+	if fields.ProjectID == "" && fields.Environment == "" {
+		return nil
 	}
-	if err := r.db.Create(&item).Error; err != nil {
-		return models.TodoItem{}, err
+	return tx.Exec(`UPDATE todo_items SET project_id = ?, environment = ?
+		WHERE project_id = '' AND environment = '' AND secret_path = ?
+		AND NOT EXISTS (SELECT 1 FROM todo_items AS claimed WHERE claimed.project_id = ? AND claimed.environment = ? AND claimed.secret_path = ?)`,
+		fields.ProjectID, fields.Environment, fields.SecretPath,
+		fields.ProjectID, fields.Environment, fields.SecretPath,
+	).Error
+}
+
+// UpsertFromWebhook 处理 Webhook 事件：如果记录存在则重置状态，如果不存在则创建。
+// 记录通过 项目 + 环境 + 路径 定位，展示用字段（项目名、密钥名、备注）会被最新载荷覆盖。
+// 同时在同一个事务中追加一条事件记录，保证状态与时间线一致。
+// notification 不为 nil 时，还会在同一个事务中把通知写入发件箱：
+// 事务提交后提醒一定已经入队，写入失败时整个 Webhook 都不会生效，发送方可以重试。
+// created 为 true 表示本次新建了记录，false 表示重置了已有记录。
+// Upsert = Update + Insert
+func (r *TodoRepository) UpsertFromWebhook(fields TodoFields, event WebhookEvent, notification *OutboxMessage, now time.Time) (item models.TodoItem, created bool, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		// 0. 认领旧版本遗留的记录
+		if err := claimLegacyTodo(tx, fields); err != nil {
+			return err
+		}
+
+		// 1. 插入或重置，只用一条 INSERT ... ON CONFLICT DO UPDATE ... RETURNING 语句。
+		// 数据库在唯一索引上原子地判断记录是否存在，并发的 Webhook 不会触发唯一键冲突，
+		// 也不存在“先查后写”之间被其他请求插入或删除的窗口。
+		// 记录已存在时重置为 "未完成" 状态，这意味着 Infisical 端发生了变更，需要重新处理这个 Todo；
+		// created_at 保持不变，上一轮的完成记录不再适用；
+		// 只有原本已完成的记录才更新 opened_at，未完成的记录继续按最初打开的时间计算等待时长。
+		// SET 中引用的 todo_items.is_completed 是更新前的值。
+		// GORM 会根据方言生成对应的语法（SQLite 3.35+ 与 PostgreSQL 都支持）。
+		item = newTodoItem(fields, now)
+		token := newInsertToken()
+		item.InsertToken = token
+		result := tx.Clauses(
+			clause.OnConflict{
+				Columns: todoIdentityColumns,
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"project_name":    fields.ProjectName,
+					"secret_name":     fields.SecretName,
+					"reminder_note":   fields.ReminderNote,
+					"is_completed":    false,
+					"completed_at":    nil, // 将字段置为 NULL
+					"completed_by":    "",
+					"completion_note": "",
+					"source":          fields.Source, // 记录最近一次触发的来源
+					"opened_at":       gorm.Expr("CASE WHEN todo_items.is_completed THEN ? ELSE todo_items.opened_at END", now),
+				}),
+			},
+			clause.Returning{},
+		).Create(&item)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// 2. RETURNING 返回的是最终的行。insert_token 只在插入时写入，重置时不更新，
+		// 因此只有本次插入的行会带回本次生成的值。
+		// 即使两个请求的时间戳相同，也只有真正插入了记录的那一个会得到 created = true。
+		created = item.InsertToken == token
+
+		// 3. 追加事件记录
+		if err := tx.Create(&models.TodoEvent{
+			TodoID:             item.ID,
+			EventType:          event.EventType,
+			ReceivedAt:         now,
+			InfisicalTimestamp: event.InfisicalTimestamp,
+			SourceIP:           event.SourceIP,
+			BodyHash:           event.BodyHash,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 4. 通知入队
+		if notification == nil {
+			return nil
+		}
+		outbox := newOutboxItem(item.ID, *notification, now)
+		return tx.Create(&outbox).Error
+	})
+	if err != nil {
+		return models.TodoItem{}, false, err
 	}
-	return item, nil
+	return item, created, nil
 }
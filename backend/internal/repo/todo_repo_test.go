@@ -0,0 +1,443 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/testdb"
+
+	"gorm.io/gorm"
+)
+
+// countRows 返回表中的记录数。
+func countRows(t *testing.T, database *gorm.DB, model interface{}) int64 {
+	t.Helper()
+	var count int64
+	if err := database.Model(model).Count(&count).Error; err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return count
+}
+
+func TestUpsertFromWebhookEnqueuesNotification(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		event := WebhookEvent{EventType: "secrets.modified"}
+		now := time.Now().UTC()
+
+		item, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/db"}, event, &OutboxMessage{Title: "t", Body: "b"}, now)
+		if err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+
+		var queued []models.NotificationOutbox
+		if err := database.Find(&queued).Error; err != nil {
+			t.Fatalf("find outbox: %v", err)
+		}
+		if len(queued) != 1 || queued[0].TodoID != item.ID || queued[0].Status != models.OutboxStatusPending {
+			t.Fatalf("outbox = %+v, want one pending message for todo %d", queued, item.ID)
+		}
+
+		// 不需要推送时不写发件箱
+		if _, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/db"}, event, nil, now); err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+		if got := countRows(t, database, &models.NotificationOutbox{}); got != 1 {
+			t.Fatalf("outbox rows = %d, want 1", got)
+		}
+	})
+}
+
+func TestUpsertFromWebhookRollsBackWhenEnqueueFails(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+
+		// 发件箱写入失败时，待办事项与事件记录都不能留下
+		if err := database.Migrator().DropTable(&models.NotificationOutbox{}); err != nil {
+			t.Fatalf("drop outbox: %v", err)
+		}
+		_, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/db"}, WebhookEvent{EventType: "secrets.modified"},
+			&OutboxMessage{Title: "t", Body: "b"}, time.Now().UTC())
+		if err == nil {
+			t.Fatal("UpsertFromWebhook succeeded without an outbox table")
+		}
+		if got := countRows(t, database, &models.TodoItem{}); got != 0 {
+			t.Errorf("todo rows = %d, want 0", got)
+		}
+		if got := countRows(t, database, &models.TodoEvent{}); got != 0 {
+			t.Errorf("event rows = %d, want 0", got)
+		}
+	})
+}
+
+func TestUpsertFromWebhookResetsExisting(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		event := WebhookEvent{EventType: "secrets.modified"}
+		fields := TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db", ProjectName: "old"}
+		first := time.Now().UTC()
+
+		created, isNew, err := todos.UpsertFromWebhook(fields, event, nil, first)
+		if err != nil || !isNew {
+			t.Fatalf("first UpsertFromWebhook = created %v, %v; want a new todo", isNew, err)
+		}
+		if _, err := todos.ToggleComplete(created.ID, first, "alice", "rotated"); err != nil {
+			t.Fatalf("ToggleComplete: %v", err)
+		}
+
+		// 同一个 项目 + 环境 + 路径 重置已有记录，并刷新展示用字段
+		fields.ProjectName = "new"
+		reset, isNew, err := todos.UpsertFromWebhook(fields, event, nil, first.Add(time.Minute))
+		if err != nil || isNew {
+			t.Fatalf("second UpsertFromWebhook = created %v, %v; want the existing todo", isNew, err)
+		}
+		if reset.ID != created.ID || reset.IsCompleted || reset.CompletedAt != nil || reset.ProjectName != "new" {
+			t.Fatalf("reset todo = %+v, want todo %d open with the new project name", reset, created.ID)
+		}
+		// 上一轮的完成记录随重置一起清空
+		stored, err := todos.GetByID(created.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if stored.CompletedBy != "" || stored.CompletionNote != "" {
+			t.Fatalf("stored todo = %+v, want completion fields cleared", stored)
+		}
+
+		// 其他环境下的同名路径是另一条待办事项
+		fields.Environment = "dev"
+		other, _, err := todos.UpsertFromWebhook(fields, event, nil, first)
+		if err != nil || other.ID == created.ID {
+			t.Fatalf("UpsertFromWebhook for another environment = id %d, %v; want a new todo", other.ID, err)
+		}
+		if got := countRows(t, database, &models.TodoItem{}); got != 2 {
+			t.Fatalf("todo rows = %d, want 2", got)
+		}
+		if got := countRows(t, database, &models.TodoEvent{}); got != 3 {
+			t.Fatalf("event rows = %d, want 3", got)
+		}
+	})
+}
+
+func TestListEvents(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		fields := TodoFields{SecretPath: "/db"}
+		start := time.Now().UTC()
+
+		// 每次投递追加一条事件，时间线按接收时间倒序排列
+		var item models.TodoItem
+		for i, eventType := range []string{"secrets.created", "secrets.modified", "secrets.deleted"} {
+			var err error
+			event := WebhookEvent{EventType: eventType, InfisicalTimestamp: int64(i), SourceIP: "10.0.0.1", BodyHash: "hash"}
+			if item, _, err = todos.UpsertFromWebhook(fields, event, nil, start.Add(time.Duration(i)*time.Minute)); err != nil {
+				t.Fatalf("UpsertFromWebhook: %v", err)
+			}
+		}
+		other, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/other"}, WebhookEvent{EventType: "secrets.modified"}, nil, start)
+		if err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+
+		timeline, err := todos.ListEvents(item.ID)
+		if err != nil {
+			t.Fatalf("ListEvents: %v", err)
+		}
+		var got []string
+		for _, event := range timeline {
+			if event.TodoID != item.ID || event.SourceIP != "10.0.0.1" || event.BodyHash != "hash" {
+				t.Fatalf("event = %+v, want an event of todo %d", event, item.ID)
+			}
+			got = append(got, event.EventType)
+		}
+		if strings.Join(got, ",") != "secrets.deleted,secrets.modified,secrets.created" {
+			t.Fatalf("timeline = %v, want the newest event first", got)
+		}
+
+		// 删除待办事项时一并删除它的时间线，不存在的待办事项返回 ErrRecordNotFound
+		if _, err := todos.Delete(item.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := todos.ListEvents(item.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("ListEvents of a deleted todo = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if got := countRows(t, database, &models.TodoEvent{}); got != 1 {
+			t.Fatalf("event rows = %d, want only the event of todo %d", got, other.ID)
+		}
+	})
+}
+
+func TestUpsertFromWebhookClaimsLegacyTodo(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		event := WebhookEvent{EventType: "secrets.modified"}
+		now := time.Now().UTC()
+
+		// 旧版本只按路径识别，遗留记录没有项目与环境
+		legacy, err := todos.Create(TodoFields{SecretPath: "/db"}, now)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := todos.ToggleComplete(legacy.ID, now, "", ""); err != nil {
+			t.Fatalf("ToggleComplete: %v", err)
+		}
+
+		// 第一个带真实项目的 Webhook 认领遗留记录并重置，而不是另建一条
+		fields := TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db"}
+		item, _, err := todos.UpsertFromWebhook(fields, event, nil, now.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+		if item.ID != legacy.ID || item.ProjectID != "p1" || item.Environment != "prod" || item.IsCompleted {
+			t.Fatalf("UpsertFromWebhook = %+v, want legacy todo %d claimed and reopened", item, legacy.ID)
+		}
+
+		// 已被认领后，其他项目的同名路径按新的身份单独建立记录
+		other, _, err := todos.UpsertFromWebhook(TodoFields{ProjectID: "p2", Environment: "prod", SecretPath: "/db"}, event, nil, now.Add(time.Minute))
+		if err != nil || other.ID == legacy.ID {
+			t.Fatalf("UpsertFromWebhook for another project = id %d, %v; want a new todo", other.ID, err)
+		}
+		if got := countRows(t, database, &models.TodoItem{}); got != 2 {
+			t.Fatalf("todo rows = %d, want 2", got)
+		}
+	})
+}
+
+func TestUpsertFromWebhookKeepsLegacyTodoWhenIdentityExists(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		event := WebhookEvent{EventType: "secrets.modified"}
+		now := time.Now().UTC()
+		fields := TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db"}
+
+		// 新身份的记录已经存在时不能认领遗留记录，否则会违反唯一索引
+		legacy, err := todos.Create(TodoFields{SecretPath: "/db"}, now)
+		if err != nil {
+			t.Fatalf("Create legacy: %v", err)
+		}
+		current, err := todos.Create(fields, now)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		item, _, err := todos.UpsertFromWebhook(fields, event, nil, now)
+		if err != nil || item.ID != current.ID {
+			t.Fatalf("UpsertFromWebhook = id %d, %v; want existing todo %d", item.ID, err, current.ID)
+		}
+		kept, err := todos.GetByID(legacy.ID)
+		if err != nil || kept.ProjectID != "" {
+			t.Fatalf("legacy todo = %+v, %v; want it unclaimed", kept, err)
+		}
+	})
+}
+
+func TestCreateDuplicateIdentity(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		fields := TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db"}
+		if _, err := todos.Create(fields, time.Now().UTC()); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		// TranslateError 把两种数据库各自的唯一约束错误统一为 gorm.ErrDuplicatedKey
+		if _, err := todos.Create(fields, time.Now().UTC()); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("duplicate Create = %v, want gorm.ErrDuplicatedKey", err)
+		}
+	})
+}
+
+func TestListEscapesLikePatterns(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		for _, path := range []string{"/a_b", "/axb", "/a%b", "/aXXb", `/a\b`, "/ab"} {
+			if _, err := todos.Create(TodoFields{SecretPath: path, Environment: "Prod"}, time.Now().UTC()); err != nil {
+				t.Fatalf("Create %s: %v", path, err)
+			}
+		}
+
+		tests := []struct {
+			name   string
+			filter TodoFilter
+			want   []string
+		}{
+			{name: "prefix with underscore", filter: TodoFilter{PathPrefix: "/a_"}, want: []string{"/a_b"}},
+			{name: "prefix with percent", filter: TodoFilter{PathPrefix: "/a%"}, want: []string{"/a%b"}},
+			{name: "prefix with backslash", filter: TodoFilter{PathPrefix: `/a\`}, want: []string{`/a\b`}},
+			{name: "search with underscore", filter: TodoFilter{Search: "a_b"}, want: []string{"/a_b"}},
+			{name: "search with percent", filter: TodoFilter{Search: "A%B"}, want: []string{"/a%b"}},
+			{name: "search is case insensitive", filter: TodoFilter{Search: "axxB"}, want: []string{"/aXXb"}},
+		}
+		for _, tt := range tests {
+			page, err := todos.List(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: List: %v", tt.name, err)
+			}
+			var got []string
+			for _, item := range page.Items {
+				got = append(got, item.SecretPath)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: List = %q, want %q", tt.name, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestListCursorByCreatedAt(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+
+		// 纳秒部分会被 PostgreSQL 截断为微秒，游标必须使用数据库中的值；
+		// 相同的 created_at 按 id 排序
+		base := time.Date(2024, 5, 1, 8, 0, 0, 123456789, time.UTC)
+		offsets := []time.Duration{0, time.Microsecond, time.Microsecond, time.Second, time.Second, 2 * time.Second, time.Hour}
+		for i, offset := range offsets {
+			if _, err := todos.Create(TodoFields{SecretPath: "/p" + strconv.Itoa(i)}, base.Add(offset)); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		for _, desc := range []bool{false, true} {
+			var want []uint
+			all, err := todos.List(TodoFilter{Sort: SortByCreatedAt, Desc: desc})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for _, item := range all.Items {
+				want = append(want, item.ID)
+			}
+
+			var got []uint
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(offsets) {
+					t.Fatalf("desc=%v: pagination did not terminate", desc)
+				}
+				page, err := todos.List(TodoFilter{Sort: SortByCreatedAt, Desc: desc, Limit: 2, Cursor: cursor})
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				for _, item := range page.Items {
+					got = append(got, item.ID)
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+			if len(want) != len(offsets) || !reflect.DeepEqual(got, want) {
+				t.Errorf("desc=%v: paged ids = %v, want %v", desc, got, want)
+			}
+		}
+	})
+}
+
+func TestUpsertFromWebhookConcurrent(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		fields := TodoFields{ProjectID: "p1", Environment: "prod", SecretPath: "/db"}
+
+		// 同一个密钥的多个 Webhook 同时到达，并且时间戳完全相同：
+		// 只能有一条记录、每个请求一条事件，且只有一个请求报告新建
+		// start 让所有请求同时开始，PostgreSQL 上它们通过连接池中的多个连接真正并发执行
+		const requests = 16
+		now := time.Now().UTC()
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			created int
+			ids     = map[uint]bool{}
+			start   = make(chan struct{})
+		)
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				item, isNew, err := todos.UpsertFromWebhook(fields, WebhookEvent{EventType: "secrets.modified"}, nil, now)
+				if err != nil {
+					t.Errorf("UpsertFromWebhook: %v", err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				ids[item.ID] = true
+				if isNew {
+					created++
+				}
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		if len(ids) != 1 {
+			t.Errorf("returned todo ids = %v, want a single id", ids)
+		}
+		if created != 1 {
+			t.Errorf("created = true for %d requests, want 1", created)
+		}
+		if got := countRows(t, database, &models.TodoItem{}); got != 1 {
+			t.Errorf("todo rows = %d, want 1", got)
+		}
+		if got := countRows(t, database, &models.TodoEvent{}); got != requests {
+			t.Errorf("event rows = %d, want %d", got, requests)
+		}
+	})
+}
+
+func TestStatsOldestOpenUsesOpenedAt(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		todos := NewTodoRepository(database)
+		event := WebhookEvent{EventType: "secrets.modified"}
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// a 很早就创建并完成，一个月后被 Webhook 重置：等待时间从重置开始计算
+		a, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/a"}, event, nil, start)
+		if err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+		if _, err := todos.Complete(a.ID, start.Add(time.Hour), "alice", ""); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		resetAt := start.AddDate(0, 1, 0)
+		if _, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/a"}, event, nil, resetAt); err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+
+		// b 在 a 重置之后才创建，但之后一直未完成，再次收到 Webhook 也不重新计时
+		bOpened := resetAt.Add(time.Hour)
+		if _, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/b"}, event, nil, bOpened); err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+		if _, _, err := todos.UpsertFromWebhook(TodoFields{SecretPath: "/b"}, event, nil, bOpened.Add(time.Hour)); err != nil {
+			t.Fatalf("UpsertFromWebhook: %v", err)
+		}
+
+		stats, err := todos.Stats()
+		if err != nil {
+			t.Fatalf("Stats: %v", err)
+		}
+		if stats.Open != 2 || stats.OldestOpenAt == nil || !stats.OldestOpenAt.Equal(resetAt) {
+			t.Fatalf("Stats = open %d oldest %v, want 2 / %v", stats.Open, stats.OldestOpenAt, resetAt)
+		}
+
+		// a 再次完成后重新打开，opened_at 更新为重新打开的时间，最早的变为 b
+		if _, err := todos.Complete(a.ID, resetAt.Add(2*time.Hour), "alice", ""); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if _, err := todos.Reopen(a.ID, resetAt.Add(3*time.Hour)); err != nil {
+			t.Fatalf("Reopen: %v", err)
+		}
+		stats, err = todos.Stats()
+		if err != nil {
+			t.Fatalf("Stats: %v", err)
+		}
+		if stats.OldestOpenAt == nil || !stats.OldestOpenAt.Equal(bOpened) {
+			t.Fatalf("Stats oldest = %v, want %v", stats.OldestOpenAt, bOpened)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// touchInterval 是更新 Token 最近使用时间的最小间隔。
+// 避免每个请求都写一次数据库。
+const touchInterval = time.Minute
+
+// TokenRepository 封装 API Token 的数据库操作。
+type TokenRepository struct {
+	db *gorm.DB
+}
+
+// NewTokenRepository 创建并返回一个新的 TokenRepository 实例。
+func NewTokenRepository(db *gorm.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// List 返回所有 Token，按 ID 倒序排列。
+func (r *TokenRepository) List() ([]models.APIToken, error) {
+	var items []models.APIToken
+	if err := r.db.Order("id desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Create 保存一个新 Token 的哈希、角色与展示前缀。
+func (r *TokenRepository) Create(name, role, tokenHash, prefix string, now time.Time) (models.APIToken, error) {
+	item := models.APIToken{
+		Name:      name,
+		Role:      role,
+		TokenHash: tokenHash,
+		Prefix:    prefix,
+		CreatedAt: now,
+	}
+	if err := r.db.Create(&item).Error; err != nil {
+		return models.APIToken{}, err
+	}
+	return item, nil
+}
+
+// Delete 根据 ID 删除（吊销）Token。
+func (r *TokenRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.APIToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Authenticate 根据 Token 哈希查找 Token，并刷新最近使用时间。
+// 找不到时返回 gorm.ErrRecordNotFound。
+func (r *TokenRepository) Authenticate(tokenHash string, now time.Time) (models.APIToken, error) {
+	var item models.APIToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&item).Error; err != nil {
+		return models.APIToken{}, err
+	}
+
+	if item.LastUsedAt == nil || now.Sub(*item.LastUsedAt) >= touchInterval {
+		if err := r.db.Model(&item).Update("last_used_at", &now).Error; err != nil {
+			return models.APIToken{}, err
+		}
+		item.LastUsedAt = &now
+	}
+	return item, nil
+}
@@ -0,0 +1,108 @@
+package repo
+
+import (
+	"time"
+
+	"backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSourceRepository 封装 Webhook 来源的数据库操作。
+type WebhookSourceRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookSourceRepository 创建并返回一个新的 WebhookSourceRepository 实例。
+func NewWebhookSourceRepository(db *gorm.DB) *WebhookSourceRepository {
+	return &WebhookSourceRepository{db: db}
+}
+
+// WebhookSourceFields 是创建或修改 Webhook 来源时可以设置的字段。
+type WebhookSourceFields struct {
+	Name         string
+	Secret       string
+	ProjectLabel string
+	Events       string
+}
+
+// List 返回所有 Webhook 来源，按 ID 升序排列。
+func (r *WebhookSourceRepository) List() ([]models.WebhookSource, error) {
+	var items []models.WebhookSource
+	if err := r.db.Order("id asc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Create 创建一个 Webhook 来源。Slug 已存在时返回 gorm.ErrDuplicatedKey。
+func (r *WebhookSourceRepository) Create(slug string, fields WebhookSourceFields, now time.Time) (models.WebhookSource, error) {
+	item := models.WebhookSource{
+		Slug:         slug,
+		Name:         fields.Name,
+		Secret:       fields.Secret,
+		ProjectLabel: fields.ProjectLabel,
+		Events:       fields.Events,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := r.db.Create(&item).Error; err != nil {
+		return models.WebhookSource{}, err
+	}
+	return item, nil
+}
+
+// FindBySlug 根据 Slug 查找 Webhook 来源，找不到时返回 gorm.ErrRecordNotFound。
+func (r *WebhookSourceRepository) FindBySlug(slug string) (models.WebhookSource, error) {
+	var item models.WebhookSource
+	if err := r.db.Where("slug = ?", slug).First(&item).Error; err != nil {
+		return models.WebhookSource{}, err
+	}
+	return item, nil
+}
+
+// Get 根据 ID 获取 Webhook 来源，找不到时返回 gorm.ErrRecordNotFound。
+func (r *WebhookSourceRepository) Get(id uint) (models.WebhookSource, error) {
+	var item models.WebhookSource
+	if err := r.db.First(&item, id).Error; err != nil {
+		return models.WebhookSource{}, err
+	}
+	return item, nil
+}
+
+// Update 覆盖 Webhook 来源的可修改字段并返回修改后的记录，找不到时返回 gorm.ErrRecordNotFound。
+func (r *WebhookSourceRepository) Update(id uint, fields WebhookSourceFields, now time.Time) (models.WebhookSource, error) {
+	var item models.WebhookSource
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WebhookSource{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"name":          fields.Name,
+			"secret":        fields.Secret,
+			"project_label": fields.ProjectLabel,
+			"events":        fields.Events,
+			"updated_at":    now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&item, id).Error
+	})
+	if err != nil {
+		return models.WebhookSource{}, err
+	}
+	return item, nil
+}
+
+// Delete 根据 ID 删除 Webhook 来源。已经创建的待办事项保留原来的来源标记。
+func (r *WebhookSourceRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.WebhookSource{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package repo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/internal/testdb"
+
+	"gorm.io/gorm"
+)
+
+func TestWebhookSourceDuplicateSlug(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, database *gorm.DB) {
+		sources := NewWebhookSourceRepository(database)
+		fields := WebhookSourceFields{Name: "infisical", Secret: "secret"}
+		if _, err := sources.Create("infisical", fields, time.Now().UTC()); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := sources.Create("infisical", fields, time.Now().UTC()); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("duplicate Create = %v, want gorm.ErrDuplicatedKey", err)
+		}
+	})
+}
@@ -3,11 +3,21 @@
 package router
 
 import (
+	"fmt"
+	"log/slog"
+	"net/http"
 	"strings"
+	"sync/atomic"
 
+	"backend/internal/auth"
 	"backend/internal/config"
+	"backend/internal/events"
 	"backend/internal/handlers"
+	"backend/internal/metrics"
 	"backend/internal/middleware"
+	"backend/internal/notify"
+	"backend/internal/reload"
+	"backend/internal/replay"
 	"backend/internal/repo"
 
 	_ "backend/docs" // 导入生成的 Swagger 文档
@@ -16,17 +26,45 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
+// Deps 汇总了 Router 需要注入到各个 Handler 的组件。
+type Deps struct {
+	TodoRepo   *repo.TodoRepository
+	OutboxRepo *repo.OutboxRepository
+	TokenRepo  *repo.TokenRepository
+
+	// WebhookSourceRepo 保存按来源区分的 Webhook 地址与 Secret。
+	WebhookSourceRepo *repo.WebhookSourceRepository
+
+	// Outbox 是通知发件箱，Webhook 入库时把提醒写入其中，未启用推送时为 nil。
+	Outbox *notify.Outbox
+
+	// Broadcaster 广播待办事项变更，供 SSE 实时推送使用。
+	Broadcaster *events.Broadcaster
+
+	// ReplayCache 记录已处理过的 Webhook 签名，拒绝重放的请求。
+	ReplayCache replay.Cache
+
+	// Reloader 在运行时重新加载配置，Router 通过它替换 CORS 来源与 Webhook Secret。
+	Reloader *reload.Manager
+}
+
 // NewRouter 构造并配置 Gin 引擎。
-// 这里进行了依赖注入：将 repo 注入到 handlers，再将 handlers 注册到路由。
-func NewRouter(cfg config.Config, repo *repo.TodoRepository) *gin.Engine {
+// 这里进行了依赖注入：将 repo 和发件箱注入到 handlers，再将 handlers 注册到路由。
+func NewRouter(cfg config.Config, deps Deps) *gin.Engine {
 	// 根据环境设置 Gin 运行模式
 	// 生产环境使用 release 模式，关闭调试日志
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Gin 在开发模式下输出的调试信息（例如注册的路由）也转到 slog
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+
 	// gin.New() 创建一个空白的 Gin 实例，不包含任何默认中间件。
 	// 相比 gin.Default()，这给了我们更多的定制空间。
 	engine := gin.New()
@@ -42,11 +80,13 @@ func NewRouter(cfg config.Config, repo *repo.TodoRepository) *gin.Engine {
 	}
 
 	// 注册全局中间件：
-	// gin.LoggerWithConfig(): 将请求日志输出到控制台，跳过健康检查端点。
-	// gin.Recovery(): 捕获任何 panic，防止程序崩溃，并返回 500 错误。
-	engine.Use(gin.LoggerWithConfig(gin.LoggerConfig{
-		SkipPaths: []string{"/health"},
-	}), gin.Recovery())
+	// otelgin.Middleware(): 为每个请求创建链路追踪的根 Span（或接上请求头中的 traceparent），跳过健康检查与指标端点。
+	// middleware.RequestID(): 读取或生成 X-Request-ID，之后的日志与错误响应都会带上它。
+	// middleware.AccessLog(): 使用 slog 记录访问日志，跳过健康检查与指标端点。
+	// middleware.Metrics(): 按路由统计请求耗时，通过 /metrics 暴露。
+	// middleware.Recovery(): 捕获任何 panic，防止程序崩溃，并返回 500 错误。
+	engine.Use(otelgin.Middleware(cfg.TracingServiceName, otelgin.WithFilter(shouldTrace)))
+	engine.Use(middleware.RequestID(), middleware.AccessLog("/health", "/metrics"), middleware.Metrics(), middleware.Recovery())
 
 	// 健康检查端点，用于容器编排和负载均衡器探测
 	// 放在全局中间件之后、业务路由之前
@@ -54,21 +94,111 @@ func NewRouter(cfg config.Config, repo *repo.TodoRepository) *gin.Engine {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Prometheus 指标端点，与健康检查一样不需要认证，供监控系统抓取
+	if cfg.MetricsEnabled {
+		engine.GET("/metrics", gin.WrapH(metrics.NewHandler(deps.TodoRepo)))
+	}
+
 	// 添加请求体大小限制中间件
 	engine.Use(middleware.BodySizeLimit(cfg.MaxBodySize))
 
 	// 配置 CORS 中间件，允许前端跨域访问
+	// WebSocket 握手的 Origin 校验也复用同一套规则
+	// 重新加载配置时整体替换校验函数，正在处理的请求不受影响
+	var corsValidator atomic.Pointer[func(string) bool]
+	setCORSValidator := func(cfg config.Config) {
+		validator := buildCORSValidator(cfg)
+		corsValidator.Store(&validator)
+	}
+	setCORSValidator(cfg)
+	allowOrigin := func(origin string) bool {
+		return (*corsValidator.Load())(origin)
+	}
 	engine.Use(cors.New(cors.Config{
-		AllowOriginFunc:  buildCORSValidator(cfg),
+		AllowOriginFunc:  allowOrigin,
 		AllowMethods:     []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Last-Event-ID", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", middleware.RequestIDHeader},
 		AllowCredentials: true,
 	}))
 
 	// 初始化业务处理器 (Handlers)
-	todoHandler := handlers.NewTodoHandler(repo)
-	webhookHandler := handlers.NewWebhookHandler(repo, cfg.WebhookSecret)
+	todoHandler := handlers.NewTodoHandler(deps.TodoRepo, deps.Broadcaster)
+	webhookHandler := handlers.NewWebhookHandler(deps.TodoRepo, deps.WebhookSourceRepo, cfg.WebhookSecrets, cfg.WebhookReplayWindow, deps.ReplayCache, deps.Outbox, deps.Broadcaster)
+	streamHandler := handlers.NewStreamHandler(deps.Broadcaster)
+	wsHandler := handlers.NewWSHandler(deps.TodoRepo, deps.Broadcaster, allowOrigin)
+	tokenHandler := handlers.NewTokenHandler(deps.TokenRepo)
+	webhookSourceHandler := handlers.NewWebhookSourceHandler(deps.WebhookSourceRepo)
+
+	// 注意：不能直接把可能为 nil 的 *notify.Outbox 赋给接口，否则会得到非 nil 的接口值
+	var waker handlers.Waker
+	if deps.Outbox != nil {
+		waker = deps.Outbox
+	}
+	notificationHandler := handlers.NewNotificationHandler(deps.OutboxRepo, waker)
+	reloadHandler := handlers.NewReloadHandler(deps.Reloader)
+
+	// 重新加载配置后替换 CORS 来源与 Webhook Secret
+	deps.Reloader.OnReload(func(next config.Config) {
+		setCORSValidator(next)
+		webhookHandler.SetSecrets(next.WebhookSecrets)
+	})
+
+	// OIDC 登录：登录、回调、登出本身不需要认证，同样在认证中间件之前注册
+	var sessions *auth.Signer
+	var oidcClient *auth.OIDCClient
+	if cfg.OIDCEnabled() {
+		sessions = auth.NewSigner(cfg.SessionSecret)
+		oidcClient = auth.NewOIDCClient(auth.OIDCConfig{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+
+			RoleClaim:      cfg.OIDCRoleClaim,
+			AdminGroups:    cfg.OIDCAdminGroups,
+			OperatorGroups: cfg.OIDCOperatorGroups,
+			DefaultRole:    cfg.OIDCDefaultRole,
+		})
+	}
+	authHandler := handlers.NewAuthHandler(oidcClient, sessions, cfg.SessionTTL, cfg.SecureCookies(), cfg.OIDCPostLoginRedirect)
+	if cfg.OIDCEnabled() {
+		engine.GET("/auth/login", authHandler.Login)
+		engine.GET("/auth/callback", authHandler.Callback)
+		engine.GET("/auth/logout", authHandler.Logout)
+	}
+
+	// Webhook 接口，用于接收外部系统 (Infisical) 的通知。
+	// 它只依赖签名校验，因此必须在认证中间件之前注册：
+	// Gin 在注册路由时就确定了中间件链，之后 Use 的中间件不会作用于它。
+	engine.POST("/api/todos/webhook", webhookHandler.Handle)
+	engine.POST("/api/webhooks/:source", webhookHandler.HandleSource)
+
+	// mTLS：配置了客户端 CA 时，之后注册的路由都要求出示有效的客户端证书。
+	// 健康检查、指标、OIDC 登录与 Webhook 在此之前注册，不受影响。
+	if cfg.ClientCertRequired() {
+		engine.Use(middleware.RequireClientCert())
+	}
+
+	// 认证中间件：之后注册的所有路由都需要携带有效的 API Token 或会话 Cookie
+	if !cfg.AuthDisabled {
+		engine.Use(middleware.Auth(deps.TokenRepo, cfg.AdminToken, sessions))
+	}
+
+	// 角色校验中间件：关闭认证时没有身份信息，直接放行
+	requireRole := func(role auth.Role) gin.HandlerFunc {
+		if cfg.AuthDisabled {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RequireRole(role)
+	}
+	viewer := requireRole(auth.RoleViewer)
+	operator := requireRole(auth.RoleOperator)
+	admin := requireRole(auth.RoleAdmin)
+
+	// 当前用户信息，前端据此判断是否需要跳转登录
+	engine.GET("/auth/me", authHandler.Me)
 
 	// 创建路由组 (Route Group)
 	// 所有以 /api/todos 开头的请求都会进入这个分组。
@@ -76,17 +206,50 @@ func NewRouter(cfg config.Config, repo *repo.TodoRepository) *gin.Engine {
 	{
 		// 注册具体的路由规则：
 
-		// Webhook 接口，用于接收外部系统 (Infisical) 的通知
-		api.POST("/webhook", webhookHandler.Handle)
-
 		// 标准 RESTful 接口
-		api.GET("", todoHandler.List)                 // 获取列表
-		api.POST("", todoHandler.Create)              // 创建
-		api.GET("/:id", todoHandler.Get)              // 获取单个待办事项
-		api.PATCH("/:id", todoHandler.ToggleComplete) // 切换完成状态
-		api.DELETE("/:id", todoHandler.Delete)        // 删除
+		api.GET("", viewer, todoHandler.List)                     // 获取列表
+		api.GET("/stream", viewer, streamHandler.Stream)          // 订阅实时变更 (SSE)
+		api.POST("", admin, todoHandler.Create)                   // 创建
+		api.GET("/:id", viewer, todoHandler.Get)                  // 获取单个待办事项
+		api.GET("/:id/events", viewer, todoHandler.ListEvents)    // 获取事件时间线
+		api.PATCH("/:id", operator, todoHandler.Update)           // 设置或切换完成状态
+		api.POST("/:id/complete", operator, todoHandler.Complete) // 标记完成
+		api.POST("/:id/reopen", operator, todoHandler.Reopen)     // 重新打开
+		api.DELETE("/:id", admin, todoHandler.Delete)             // 删除
+	}
+
+	// WebSocket 实时通道：按条件订阅变更，并可在同一连接上标记完成 / 重新打开。
+	// 连接本身只需要 viewer 角色，修改操作在消息处理时单独校验 operator 角色。
+	engine.GET("/api/ws", viewer, wsHandler.Serve)
+
+	// 通知发件箱管理接口：查看发送失败的通知并重新入队
+	notifications := engine.Group("/api/notifications", operator)
+	{
+		notifications.GET("/failed", notificationHandler.ListFailed)
+		notifications.POST("/:id/requeue", notificationHandler.Requeue)
+	}
+
+	// API Token 管理接口，仅限管理员
+	tokens := engine.Group("/api/tokens", admin)
+	{
+		tokens.GET("", tokenHandler.List)
+		tokens.POST("", tokenHandler.Create)
+		tokens.DELETE("/:id", tokenHandler.Delete)
 	}
 
+	// Webhook 来源管理接口，仅限管理员
+	webhookSources := engine.Group("/api/webhook-sources", admin)
+	{
+		webhookSources.GET("", webhookSourceHandler.List)
+		webhookSources.POST("", webhookSourceHandler.Create)
+		webhookSources.PATCH("/:id", webhookSourceHandler.Update)
+		webhookSources.DELETE("/:id", webhookSourceHandler.Delete)
+	}
+
+	// 配置重新加载接口，仅限管理员
+	engine.POST("/admin/reload", admin, reloadHandler.Reload)
+	engine.GET("/admin/reload", admin, reloadHandler.Status)
+
 	// 注册 Swagger UI 路由
 	// 访问 http://localhost:8080/swagger/index.html 查看 API 文档
 	engine.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -100,6 +263,11 @@ func NewRouter(cfg config.Config, repo *repo.TodoRepository) *gin.Engine {
 	return engine
 }
 
+// shouldTrace 决定请求是否需要链路追踪：监控系统频繁访问的端点没有排查价值，不记录。
+func shouldTrace(r *http.Request) bool {
+	return r.URL.Path != "/health" && r.URL.Path != "/metrics"
+}
+
 // buildCORSValidator 根据配置构建 CORS 来源验证函数。
 // 开发模式：允许 localhost 和 127.0.0.1 的所有端口
 // 生产模式：只允许配置的特定域名
@@ -0,0 +1,197 @@
+package router
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/auth"
+	"backend/internal/config"
+	"backend/internal/events"
+	"backend/internal/reload"
+	"backend/internal/replay"
+	"backend/internal/repo"
+	"backend/internal/signature"
+	"backend/internal/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// testRouter 是挂载了全部路由的引擎，以及每个角色各一个 API Token。
+type testRouter struct {
+	engine  *gin.Engine
+	todos   *repo.TodoRepository
+	tokens  *repo.TokenRepository
+	sources *repo.WebhookSourceRepository
+	bearer  map[auth.Role]string
+}
+
+func newTestRouter(t *testing.T) *testRouter {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	database := testdb.SQLite(t)
+	cfg := config.Config{
+		MaxBodySize:         1 << 20,
+		AdminToken:          "bootstrap-secret",
+		WebhookSecrets:      []signature.Key{{ID: "test", Secret: testWebhookSecret}},
+		WebhookReplayWindow: 5 * time.Minute,
+	}
+	r := &testRouter{
+		todos:   repo.NewTodoRepository(database),
+		tokens:  repo.NewTokenRepository(database),
+		sources: repo.NewWebhookSourceRepository(database),
+		bearer:  map[auth.Role]string{},
+	}
+	r.engine = NewRouter(cfg, Deps{
+		TodoRepo:          r.todos,
+		OutboxRepo:        repo.NewOutboxRepository(database),
+		TokenRepo:         r.tokens,
+		WebhookSourceRepo: r.sources,
+		Broadcaster:       events.NewBroadcaster(0),
+		ReplayCache:       replay.NewMemoryCache(cfg.WebhookReplayWindow),
+		Reloader:          reload.NewManager(cfg, func() (config.Config, error) { return cfg, nil }),
+	})
+
+	for _, role := range []auth.Role{auth.RoleViewer, auth.RoleOperator, auth.RoleAdmin} {
+		token, err := auth.GenerateToken()
+		if err != nil {
+			t.Fatalf("GenerateToken: %v", err)
+		}
+		if _, err := r.tokens.Create(string(role), string(role), auth.HashToken(token), auth.DisplayPrefix(token), time.Now().UTC()); err != nil {
+			t.Fatalf("create %s token: %v", role, err)
+		}
+		r.bearer[role] = token
+	}
+	return r
+}
+
+// do 发送请求，token 为空时不携带 Authorization 头。
+func (r *testRouter) do(method, path, token, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.engine.ServeHTTP(w, req)
+	return w
+}
+
+// newTodo 创建一条待办事项，返回它的路径。
+func (r *testRouter) newTodo(t *testing.T) string {
+	t.Helper()
+	item, err := r.todos.Create(repo.TodoFields{SecretPath: fmt.Sprintf("/%d", time.Now().UnixNano())}, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return fmt.Sprintf("/api/todos/%d", item.ID)
+}
+
+func TestPublicRoutes(t *testing.T) {
+	r := newTestRouter(t)
+
+	if w := r.do(http.MethodGet, "/health", "", "", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET /health = %d, want 200", w.Code)
+	}
+
+	// Webhook 只依赖签名，不需要 API Token
+	body := fmt.Sprintf(`{"event":"secrets.modified","project":{"projectId":"p1","environment":"prod","secretPath":"/db"},"timestamp":%d}`, time.Now().UnixMilli())
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(body))
+	header := http.Header{}
+	header.Set("X-Infisical-Signature", fmt.Sprintf("t=%d;sha256=%s", time.Now().UnixMilli(), hex.EncodeToString(mac.Sum(nil))))
+	if w := r.do(http.MethodPost, "/api/todos/webhook", "", body, header); w.Code != http.StatusOK {
+		t.Fatalf("POST /api/todos/webhook = %d %s, want 200", w.Code, w.Body)
+	}
+
+	// 其余接口需要认证
+	for _, path := range []string{"/api/todos", "/api/tokens", "/api/webhook-sources", "/admin/reload", "/auth/me"} {
+		if w := r.do(http.MethodGet, path, "", "", nil); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s without token = %d, want 401", path, w.Code)
+		}
+	}
+}
+
+func TestRouteRoles(t *testing.T) {
+	r := newTestRouter(t)
+
+	// path 在每次请求前调用，需要目标记录的接口每次使用一条新的记录
+	fixed := func(path string) func(*testing.T) string {
+		return func(*testing.T) string { return path }
+	}
+	newToken := func(t *testing.T) string {
+		t.Helper()
+		item, err := r.tokens.Create("victim", string(auth.RoleViewer), auth.HashToken(time.Now().String()), "itn_x", time.Now().UTC())
+		if err != nil {
+			t.Fatalf("create token: %v", err)
+		}
+		return fmt.Sprintf("/api/tokens/%d", item.ID)
+	}
+	sources := 0
+	newSourceBody := func() string {
+		sources++
+		return fmt.Sprintf(`{"slug": "created-%d", "name": "source"}`, sources)
+	}
+	newSource := func(t *testing.T) string {
+		t.Helper()
+		sources++
+		item, err := r.sources.Create(fmt.Sprintf("source-%d", sources), repo.WebhookSourceFields{Name: "source", Secret: "secret"}, time.Now().UTC())
+		if err != nil {
+			t.Fatalf("create webhook source: %v", err)
+		}
+		return fmt.Sprintf("/api/webhook-sources/%d", item.ID)
+	}
+
+	routes := []struct {
+		method string
+		path   func(*testing.T) string
+		body   func() string
+		role   auth.Role
+	}{
+		{method: http.MethodGet, path: fixed("/api/todos"), role: auth.RoleViewer},
+		{method: http.MethodGet, path: r.newTodo, role: auth.RoleViewer},
+		{method: http.MethodPost, path: fixed("/api/todos"), body: func() string { return fmt.Sprintf(`{"secretPath": "/created/%d"}`, time.Now().UnixNano()) }, role: auth.RoleAdmin},
+		{method: http.MethodPatch, path: r.newTodo, role: auth.RoleOperator},
+		{method: http.MethodPost, path: func(t *testing.T) string { return r.newTodo(t) + "/complete" }, role: auth.RoleOperator},
+		{method: http.MethodPost, path: func(t *testing.T) string { return r.newTodo(t) + "/reopen" }, role: auth.RoleOperator},
+		{method: http.MethodDelete, path: r.newTodo, role: auth.RoleAdmin},
+		{method: http.MethodGet, path: fixed("/api/notifications/failed"), role: auth.RoleOperator},
+		{method: http.MethodGet, path: fixed("/api/tokens"), role: auth.RoleAdmin},
+		{method: http.MethodPost, path: fixed("/api/tokens"), body: func() string { return `{"name": "ci"}` }, role: auth.RoleAdmin},
+		{method: http.MethodDelete, path: newToken, role: auth.RoleAdmin},
+		{method: http.MethodGet, path: fixed("/api/webhook-sources"), role: auth.RoleAdmin},
+		{method: http.MethodPost, path: fixed("/api/webhook-sources"), body: newSourceBody, role: auth.RoleAdmin},
+		{method: http.MethodPatch, path: newSource, body: func() string { return `{"name": "renamed"}` }, role: auth.RoleAdmin},
+		{method: http.MethodDelete, path: newSource, role: auth.RoleAdmin},
+		{method: http.MethodGet, path: fixed("/admin/reload"), role: auth.RoleAdmin},
+		{method: http.MethodPost, path: fixed("/admin/reload"), role: auth.RoleAdmin},
+	}
+	for _, route := range routes {
+		for _, role := range []auth.Role{auth.RoleViewer, auth.RoleOperator, auth.RoleAdmin} {
+			path := route.path(t)
+			body := ""
+			if route.body != nil {
+				body = route.body()
+			}
+			w := r.do(route.method, path, r.bearer[role], body, nil)
+			if role.Allows(route.role) {
+				if w.Code < 200 || w.Code > 299 {
+					t.Errorf("%s %s as %s = %d %s, want 2xx", route.method, path, role, w.Code, w.Body)
+				}
+			} else if w.Code != http.StatusForbidden {
+				t.Errorf("%s %s as %s = %d, want 403", route.method, path, role, w.Code)
+			}
+		}
+	}
+}
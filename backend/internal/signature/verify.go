@@ -14,48 +14,84 @@ import (
 	"time"
 )
 
-// replayWindow 定义了时间戳的有效窗口（5分钟）。
+// DefaultReplayWindow 定义了时间戳的默认有效窗口（5分钟）。
 // 用于防止重放攻击 (Replay Attack)。
-const replayWindow = 5 * time.Minute
+const DefaultReplayWindow = 5 * time.Minute
+
+// Key 是一个用于验证签名的 Webhook Secret。
+// 同时配置多个 Key 时，可以先在后端加入新 Secret，再到 Infisical 中替换，最后移除旧 Secret，
+// 轮换期间两边的 Secret 不必同时修改。
+type Key struct {
+	// ID 是 Secret 的标识，只用于日志，不参与签名计算。
+	ID string
+
+	// Secret 是 Webhook Secret 本身。
+	Secret string
+
+	// ExpiresAt 是 Secret 的过期时间，零值表示永不过期。
+	ExpiresAt time.Time
+}
+
+// Active 判断 Secret 在 now 时刻是否仍然有效。
+func (k Key) Active(now time.Time) bool {
+	return k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt)
+}
+
+// ErrNoActiveKey 表示配置的 Secret 都已过期。
+var ErrNoActiveKey = errors.New("no active webhook secret")
+
+// ErrStaleTimestamp 表示时间戳缺失或不在允许的窗口内。
+var ErrStaleTimestamp = errors.New("timestamp out of range")
 
 // VerifySignature 验证 Infisical 的 Webhook 签名。
 // 参数：
 // - bodyText: HTTP 请求体的原始内容。
 // - headerValue: x-infisical-signature 头的值。
-// - secret: 配置的 Webhook Secret。
-// - now: 当前时间，用于校验时间戳。
-func VerifySignature(bodyText, headerValue, secret string, now time.Time) error {
+// - keys: 配置的 Webhook Secret 列表，任意一个未过期的 Secret 匹配即验证通过。
+// - now: 当前时间，用于校验时间戳和 Secret 是否过期。
+// - window: 时间戳允许的误差范围。
+//
+// 验证通过时返回匹配的 Secret 的 ID，以及签名摘要的十六进制形式，调用方可以用摘要识别重放的请求。
+// 摘要取自解码后的签名，同一个签名换用 Hex 或 Base64 编码得到的结果相同。
+func VerifySignature(bodyText, headerValue string, keys []Key, now time.Time, window time.Duration) (keyID, digest string, err error) {
 	// 1. 解析签名头
 	// 格式通常为: t=1234567890;sha256=abcdef...
 	timestamp, signature, err := parseSignatureHeader(headerValue)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
 	// 2. 校验时间戳新鲜度
-	// 确保请求是在最近 5 分钟内发出的。
-	if !isTimestampFresh(timestamp, now) {
-		return errors.New("timestamp out of range")
+	// 确保请求是在最近的时间窗口内发出的。
+	if !isTimestampFresh(timestamp, now, window) {
+		return "", "", ErrStaleTimestamp
 	}
 
-	// 3. 计算期望的 HMAC 值
-	// Infisical 实际只对 payload 进行 HMAC-SHA256 哈希运算。
-	expected := computeHMAC(bodyText, secret)
-
-	// 4. 解码请求中的签名
+	// 3. 解码请求中的签名
 	// 签名可能是 Hex 或 Base64 编码。
 	decoded, err := decodeSignature(signature)
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	// 5. 比较签名
+	// 4. 依次用每个未过期的 Secret 计算期望的 HMAC 值并比较
+	// Infisical 实际只对 payload 进行 HMAC-SHA256 哈希运算。
 	// 使用 hmac.Equal 防止时序攻击 (Timing Attack)。
-	if !hmac.Equal(decoded, expected) {
-		return errors.New("signature mismatch")
+	active := 0
+	for _, key := range keys {
+		if !key.Active(now) {
+			continue
+		}
+		active++
+		if hmac.Equal(decoded, computeHMAC(bodyText, key.Secret)) {
+			return key.ID, hex.EncodeToString(decoded), nil
+		}
+	}
+	if active == 0 {
+		return "", "", ErrNoActiveKey
 	}
 
-	return nil
+	return "", "", errors.New("signature mismatch")
 }
 
 // parseSignatureHeader 解析类似 "t=123456;sha256=xyz" 的头。
@@ -87,16 +123,32 @@ func parseSignatureHeader(headerValue string) (int64, string, error) {
 		return 0, "", fmt.Errorf("invalid timestamp: %w", err)
 	}
 
-	// 简单的逻辑判断时间戳单位（毫秒 vs 秒），兼容不同格式
+	return normalizeTimestamp(timestamp), signature, nil
+}
+
+// CheckTimestamp 校验 Webhook 载荷中的 timestamp 是否在窗口内，返回它对应的时间。
+//
+// 签名只覆盖请求体，签名头中的 t= 可以被截获请求的一方随意替换，
+// 只有载荷中的 timestamp 受签名保护，判断请求是否过期（以及重放缓存保留摘要多久）应以它为准。
+func CheckTimestamp(timestamp int64, now time.Time, window time.Duration) (time.Time, error) {
+	timestamp = normalizeTimestamp(timestamp)
+	if !isTimestampFresh(timestamp, now, window) {
+		return time.Time{}, ErrStaleTimestamp
+	}
+	return time.Unix(timestamp, 0).UTC(), nil
+}
+
+// normalizeTimestamp 把毫秒时间戳转换为秒。
+// 简单的逻辑判断时间戳单位（毫秒 vs 秒），兼容不同格式
+func normalizeTimestamp(timestamp int64) int64 {
 	if timestamp > 1_000_000_000_000 {
 		timestamp = timestamp / 1000
 	}
-
-	return timestamp, signature, nil
+	return timestamp
 }
 
 // isTimestampFresh 检查时间戳是否在允许的误差范围内。
-func isTimestampFresh(timestamp int64, now time.Time) bool {
+func isTimestampFresh(timestamp int64, now time.Time, window time.Duration) bool {
 	signedAt := time.Unix(timestamp, 0)
 	if timestamp <= 0 {
 		return false
@@ -111,7 +163,7 @@ func isTimestampFresh(timestamp int64, now time.Time) bool {
 		diff = -diff
 	}
 
-	return diff <= replayWindow
+	return diff <= window
 }
 
 // computeHMAC 使用 SHA256 计算 HMAC。
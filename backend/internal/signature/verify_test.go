@@ -0,0 +1,92 @@
+package signature
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	window := 5 * time.Minute
+
+	tests := []struct {
+		name      string
+		timestamp int64
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "seconds", timestamp: now.Unix(), want: now},
+		{name: "milliseconds", timestamp: now.UnixMilli() - 1500, want: now.Add(-2 * time.Second)},
+		{name: "edge of window", timestamp: now.Add(-window).Unix(), want: now.Add(-window)},
+		{name: "too old", timestamp: now.Add(-window - time.Second).Unix(), wantErr: true},
+		{name: "too far in the future", timestamp: now.Add(window + time.Second).UnixMilli(), wantErr: true},
+		{name: "missing", timestamp: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := CheckTimestamp(tt.timestamp, now, window)
+		if tt.wantErr {
+			if !errors.Is(err, ErrStaleTimestamp) {
+				t.Errorf("%s: CheckTimestamp = %v, %v; want ErrStaleTimestamp", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s: CheckTimestamp = %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	window := 5 * time.Minute
+	body := `{"event":"secrets.modified"}`
+
+	// sign 返回用 secret 签名 body 的签名头，encode 决定签名的编码方式
+	sign := func(secret string, signedAt time.Time, encode func([]byte) string) string {
+		return fmt.Sprintf("t=%d;sha256=%s", signedAt.UnixMilli(), encode(computeHMAC(body, secret)))
+	}
+	keys := []Key{
+		{ID: "old", Secret: "old-secret", ExpiresAt: now.Add(-time.Hour)},
+		{ID: "current", Secret: "current-secret"},
+		{ID: "next", Secret: "next-secret", ExpiresAt: now.Add(time.Hour)},
+	}
+	digest := hex.EncodeToString(computeHMAC(body, "current-secret"))
+
+	tests := []struct {
+		name       string
+		header     string
+		keys       []Key
+		wantKeyID  string
+		wantDigest string
+		wantErr    bool
+		// wantIs 不为 nil 时，错误还必须是这个导出的错误
+		wantIs error
+	}{
+		{name: "first active key", header: sign("current-secret", now, hex.EncodeToString), keys: keys, wantKeyID: "current", wantDigest: digest},
+		{name: "second active key", header: sign("next-secret", now, hex.EncodeToString), keys: keys, wantKeyID: "next"},
+		{name: "base64 signature", header: sign("current-secret", now, base64.StdEncoding.EncodeToString), keys: keys, wantKeyID: "current", wantDigest: digest},
+		// 已过期的 Secret 不再参与验证，即使签名本身正确
+		{name: "expired key", header: sign("old-secret", now, hex.EncodeToString), keys: keys, wantErr: true},
+		{name: "unknown secret", header: sign("other-secret", now, hex.EncodeToString), keys: keys, wantErr: true},
+		{name: "all keys expired", header: sign("old-secret", now, hex.EncodeToString), keys: keys[:1], wantErr: true, wantIs: ErrNoActiveKey},
+		{name: "no keys", header: sign("current-secret", now, hex.EncodeToString), wantErr: true, wantIs: ErrNoActiveKey},
+		{name: "stale header", header: sign("current-secret", now.Add(-window-time.Second), hex.EncodeToString), keys: keys, wantErr: true, wantIs: ErrStaleTimestamp},
+		{name: "malformed header", header: "sha256=abc", keys: keys, wantErr: true},
+	}
+	for _, tt := range tests {
+		keyID, gotDigest, err := VerifySignature(body, tt.header, tt.keys, now, window)
+		if tt.wantErr {
+			if err == nil || (tt.wantIs != nil && !errors.Is(err, tt.wantIs)) {
+				t.Errorf("%s: VerifySignature = %q, %v; want error %v", tt.name, keyID, err, tt.wantIs)
+			}
+			continue
+		}
+		if err != nil || keyID != tt.wantKeyID || (tt.wantDigest != "" && gotDigest != tt.wantDigest) {
+			t.Errorf("%s: VerifySignature = %q, %q, %v; want %q, %q", tt.name, keyID, gotDigest, err, tt.wantKeyID, tt.wantDigest)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey 是当前语句的 Span 在 gorm.DB 实例中的存储键。
+const gormSpanKey = "tracing:span"
+
+// gormPlugin 为每条 SQL 语句创建一个 Span。
+// 没有使用 gorm.io/plugin/opentelemetry：它为了识别各种数据库直接依赖了 ClickHouse 等驱动，
+// 而我们只需要记录语句耗时与结果。
+type gormPlugin struct{}
+
+// GORMPlugin 返回为 SQL 语句创建 Span 的 GORM 插件。
+// Span 的父节点取自 db.WithContext 传入的 ctx；没有父 Span 的语句（例如发件箱的后台轮询）不记录，
+// 避免每次轮询都产生一条独立的 Trace。
+// 只记录带占位符的 SQL，不记录查询参数，避免把 Webhook Secret、Token 哈希等写入追踪后端。
+func GORMPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+// Name 实现 gorm.Plugin 接口。
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize 实现 gorm.Plugin 接口，在每类操作的执行前后注册回调。
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", startGORMSpan("INSERT")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", endGORMSpan),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", startGORMSpan("SELECT")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", endGORMSpan),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", startGORMSpan("UPDATE")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", endGORMSpan),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startGORMSpan("DELETE")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", endGORMSpan),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", startGORMSpan("ROW")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", endGORMSpan),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startGORMSpan("RAW")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", endGORMSpan),
+	)
+}
+
+// startGORMSpan 返回在语句执行前创建 Span 的回调，Span 名称为 "操作 表名"，例如 "SELECT todo_items"。
+func startGORMSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system.name", db.Dialector.Name()),
+				attribute.String("db.operation.name", operation),
+				attribute.String("db.collection.name", db.Statement.Table),
+			),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// endGORMSpan 在语句执行后结束 Span，并记录 SQL、影响行数与错误。
+// 记录不存在（gorm.ErrRecordNotFound）是正常的查询结果，不标记为错误。
+func endGORMSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.query.text", db.Statement.SQL.String()),
+		attribute.Int64("db.response.returned_rows", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
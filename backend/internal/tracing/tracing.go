@@ -0,0 +1,147 @@
+// Package tracing 负责 OpenTelemetry 链路追踪的初始化。
+// 一次 Webhook 从进入服务到推送通知会经过签名验证、数据库写入、发件箱排队与 Apprise 调用，
+// 链路追踪把这些步骤串成一条 Trace，用于定位提醒延迟发生在哪一步。
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 支持的导出方式，对应 OTEL_TRACES_EXPORTER。
+const (
+	// ExporterNone 关闭链路追踪（默认），使用 OpenTelemetry 的全局空实现，几乎没有开销。
+	ExporterNone = "none"
+
+	// ExporterOTLP 通过 OTLP/HTTP 导出到 Collector 或兼容的后端（Jaeger、Tempo 等）。
+	ExporterOTLP = "otlp"
+
+	// ExporterNoop 正常生成 Span 但直接丢弃，用于测试或评估追踪本身的开销。
+	ExporterNoop = "noop"
+)
+
+// DefaultServiceName 是未设置 OTEL_SERVICE_NAME 时使用的服务名。
+const DefaultServiceName = "infisical-notification"
+
+// instrumentationName 是本服务手动创建的 Span 所属的 Tracer 名称。
+const instrumentationName = "backend"
+
+// Options 描述链路追踪的配置，由 config.Config 提供。
+type Options struct {
+	Exporter    string
+	Endpoint    string            // OTLP 接收地址，例如 http://otel-collector:4318
+	Headers     map[string]string // 发送给 OTLP 接收端的额外请求头，例如认证信息
+	ServiceName string
+	SampleRatio float64 // 采样比例，0 ~ 1
+}
+
+// Setup 根据配置初始化全局 TracerProvider 与上下文传播方式。
+// 返回的 shutdown 应在程序退出前调用，把尚未导出的 Span 发送出去。
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	// 无论是否启用追踪都设置 W3C Trace Context 传播，
+	// 这样上游传入的 traceparent 至少能原样传递给下游。
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterNoop:
+		exporter = NoopExporter{}
+	case ExporterOTLP:
+		clientOpts := []otlptracehttp.Option{}
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		if len(opts.Headers) > 0 {
+			clientOpts = append(clientOpts, otlptracehttp.WithHeaders(opts.Headers))
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", opts.Exporter)
+	}
+
+	provider, err := NewProvider(ctx, exporter, opts.ServiceName, opts.SampleRatio)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider 使用给定的 exporter 创建 TracerProvider。
+// 采样遵循上游的决定（ParentBased），没有上游时按 sampleRatio 采样。
+// 测试中可以传入 NoopExporter 或内存 exporter。
+func NewProvider(ctx context.Context, exporter sdktrace.SpanExporter, serviceName string, sampleRatio float64) (*sdktrace.TracerProvider, error) {
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	), nil
+}
+
+// NoopExporter 是一个丢弃所有 Span 的 exporter。
+type NoopExporter struct{}
+
+// ExportSpans 实现 sdktrace.SpanExporter 接口。
+func (NoopExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error { return nil }
+
+// Shutdown 实现 sdktrace.SpanExporter 接口。
+func (NoopExporter) Shutdown(context.Context) error { return nil }
+
+// Tracer 返回本服务手动创建 Span 时使用的 Tracer。
+// 每次调用都从全局 TracerProvider 获取，因此在 Setup 之前调用也能在之后生效。
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Transport 包装 HTTP Transport，为每个外发请求创建 Span 并注入 traceparent 请求头。
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// traceParentHeader 是 W3C Trace Context 的请求头名称。
+const traceParentHeader = "traceparent"
+
+// TraceParent 返回 ctx 中当前 Span 的 W3C traceparent 值，没有有效的 Span 时返回空字符串。
+// 用于把 Trace 跨越异步边界（例如通知发件箱）传递下去。
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier[traceParentHeader]
+}
+
+// ContextWithTraceParent 把 TraceParent 保存的值还原到 ctx 中，之后创建的 Span 会成为它的子节点。
+// traceParent 为空或无法解析时原样返回 ctx。
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/testdb"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useRecorder 把全局 TracerProvider 换成同步写入内存的实现，测试结束后恢复。
+func useRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	useRecorder(t)
+
+	if got := TraceParent(context.Background()); got != "" {
+		t.Fatalf("TraceParent without span = %q, want empty", got)
+	}
+	if ctx := ContextWithTraceParent(context.Background(), ""); ctx != context.Background() {
+		t.Fatal("ContextWithTraceParent with empty value changed ctx")
+	}
+
+	// 跨越发件箱之后创建的 Span 属于入队时的同一条 Trace
+	ctx, parent := Tracer().Start(context.Background(), "webhook")
+	defer parent.End()
+	traceParent := TraceParent(ctx)
+	if traceParent == "" {
+		t.Fatal("TraceParent with span is empty")
+	}
+	_, child := Tracer().Start(ContextWithTraceParent(context.Background(), traceParent), "notify.deliver")
+	defer child.End()
+	if child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("child trace %s, want %s", child.SpanContext().TraceID(), parent.SpanContext().TraceID())
+	}
+}
+
+type tracingTestRow struct {
+	ID   uint
+	Name string
+}
+
+func TestGORMPlugin(t *testing.T) {
+	exporter := useRecorder(t)
+	database := testdb.SQLite(t)
+	if err := database.Use(GORMPlugin()); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := database.AutoMigrate(&tracingTestRow{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	// 没有父 Span 的语句不记录
+	exporter.Reset()
+	if err := database.Create(&tracingTestRow{Name: "a"}).Error; err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("spans without parent = %d, want 0", len(spans))
+	}
+
+	// 有父 Span 时每条语句一个子 Span，不记录查询参数
+	ctx, parent := Tracer().Start(context.Background(), "request")
+	if err := database.WithContext(ctx).Where("name = ?", "secret-value").Find(&[]tracingTestRow{}).Error; err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	parent.End()
+
+	var found bool
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "SELECT tracing_test_rows" {
+			continue
+		}
+		found = true
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("SELECT span parent = %s, want %s", span.Parent.SpanID(), parent.SpanContext().SpanID())
+		}
+		for _, attr := range span.Attributes {
+			if attr.Key == "db.query.text" && attr.Value.AsString() != "SELECT * FROM `tracing_test_rows` WHERE name = ?" {
+				t.Fatalf("db.query.text = %q, want the statement with placeholders", attr.Value.AsString())
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no SELECT span in %v", exporter.GetSpans())
+	}
+}
@@ -13,23 +13,44 @@
 //	@license.url				https://opensource.org/licenses/MIT
 //
 //	@host						localhost:8080
-//	@BasePath					/api/todos
+//	@BasePath					/api
 //	@schemes					http https
+//
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				API Token，格式：Bearer <token>
 
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
 	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"backend/internal/certs"
 	"backend/internal/config"
 	"backend/internal/db"
-	"backend/internal/models"
+	"backend/internal/events"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/migrations"
+	"backend/internal/notify"
+	"backend/internal/reload"
+	"backend/internal/replay"
 	"backend/internal/repo"
 	"backend/internal/router"
+	"backend/internal/tracing"
 )
 
 // main 函数是程序的执行入口,类似于 Python 的 if __name__ == "__main__": 下的代码。
@@ -40,13 +61,48 @@ func main() {
 	// 生产环境通常直接使用系统环境变量,所以这里忽略文件不存在的错误。
 	_ = godotenv.Load()
 
+	// 尽早接管 SIGHUP：进程默认收到 SIGHUP 会退出，
+	// 启动期间（连接数据库、执行迁移）收到的信号在重新加载配置的 Worker 启动后处理。
+	hangup := reload.NotifySignal()
+
+	// 读取配置来源：配置文件（--config 或 TODO_CONFIG_FILE）、环境变量与命令行参数。
+	// 命令行参数需要写在子命令之前，例如：go run main.go --config config.yaml migrate up
+	src, args, err := config.ParseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("读取配置失败", "error", err)
+	}
+	if len(args) > 0 && args[0] != "migrate" && args[0] != "config" {
+		fatal("未知的子命令", "command", args[0])
+	}
+
+	// 初始化日志
+	// 所有日志统一通过 slog 输出，格式与级别由 log.format、log.level（LOG_FORMAT、LOG_LEVEL）控制。
+	// 在加载其余配置之前完成，这样加载配置时输出的警告也使用同样的格式。
+	logFormat, logLevel, err := config.LoadLogging(src)
+	if err != nil {
+		fatal("日志配置无效", "error", err)
+	}
+	logging.Setup(os.Stderr, logFormat, logLevel)
+
+	// config 子命令只校验或输出配置，不连接数据库。
+	// 用法：go run main.go [--config config.yaml] config validate | print [--redacted]
+	if len(args) > 0 && args[0] == "config" {
+		if err := config.RunCommand(src, args[1:], os.Stdout); err != nil {
+			fatal("执行 config 命令失败", "error", err)
+		}
+		return
+	}
+
 	// 1. 加载配置
-	// 从环境变量中读取配置信息,如果未设置则使用默认值。
-	// 这符合 "12-Factor App" 的推荐实践。
-	cfg, err := config.Load()
+	// 依次读取配置文件、环境变量与命令行参数，后者覆盖前者，未设置的配置项使用默认值。
+	// 生产环境仍然可以只使用环境变量，符合 "12-Factor App" 的推荐实践。
+	cfg, err := config.Load(src)
 	if err != nil {
-		// log.Fatal 会打印错误日志并以非零状态码退出程序 (os.Exit(1))。
-		log.Fatal(err)
+		// fatal 会记录错误日志并以非零状态码退出程序 (os.Exit(1))。
+		fatal("加载配置失败", "error", err)
 	}
 
 	// 打印启动配置信息，便于排查问题
@@ -58,37 +114,242 @@ func main() {
 		"environment", cfg.Environment,
 		"bind_addr", cfg.BindAddr,
 		"cors_origins", corsDisplay,
+		"notification_enabled", cfg.NotificationEnabled(),
+		"auth_enabled", !cfg.AuthDisabled,
+		"oidc_enabled", cfg.OIDCEnabled(),
+		"webhook_key_ids", cfg.WebhookKeyIDs(),
+		"webhook_replay_store", cfg.WebhookReplayStore,
+		"trace_exporter", cfg.TracingExporter,
+		"tls_enabled", cfg.TLSEnabled(),
 	)
 
+	// 初始化链路追踪
+	// 未启用时使用 OpenTelemetry 的全局空实现，下面各处的埋点几乎没有开销。
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingOptions())
+	if err != nil {
+		fatal("初始化链路追踪失败", "error", err)
+	}
+
 	// 2. 初始化数据库连接
-	// 使用 GORM 连接 SQLite 数据库。
-	// 这里会处理数据库文件的创建和连接池的设置。
-	database, err := db.Open(cfg.DBPath)
+	// 根据 DSN 前缀选择 SQLite 或 PostgreSQL。
+	// 这里会处理 SQLite 数据库文件的创建和连接池的设置。
+	database, err := db.Open(cfg.DBDSN, db.Options{MaxOpenConns: cfg.DBMaxOpenConns})
 	if err != nil {
-		log.Fatal(err)
+		fatal("数据库连接失败", "error", err)
+	}
+	// 只记录驱动名称，DSN 中可能包含数据库密码
+	slog.Info("数据库已连接", "driver", database.Dialector.Name())
+
+	// 为每条 SQL 语句创建 Span，记录排队等待连接（SQLite 只有一个连接）与执行的耗时
+	if err := database.Use(tracing.GORMPlugin()); err != nil {
+		fatal("注册数据库链路追踪插件失败", "error", err)
+	}
+	// 按操作与表记录 SQL 语句的耗时与错误，暴露在 /metrics
+	if err := database.Use(metrics.GORMPlugin()); err != nil {
+		fatal("注册数据库监控指标插件失败", "error", err)
 	}
 
-	// 3. 自动迁移 (Auto Migration)
-	// GORM 的一个强大功能,它会根据 Go 的结构体定义自动创建或更新数据库表结构。
-	// 类似于 Django 的 makemigrations/migrate 或 Flask-Migrate,但它是运行时自动完成的。
-	// 这里确保 todo_items 表存在且字段正确。
-	if err := database.AutoMigrate(&models.TodoItem{}); err != nil {
-		log.Fatal(err)
+	// migrate 子命令只管理数据库表结构，不启动 Web 服务。
+	// 用法：go run main.go migrate up | down [n] | status
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrations.RunCommand(database, args[1:], os.Stdout); err != nil {
+			fatal("执行 migrate 命令失败", "error", err)
+		}
+		return
+	}
+
+	// 3. 数据库迁移
+	// 表结构由 internal/migrations 中带版本号的迁移维护，已执行的版本记录在 schema_migrations 表中。
+	// 数据库版本比程序新时（例如回退了程序版本）拒绝启动，避免旧代码写坏新结构的数据。
+	pending, err := migrations.Check(database)
+	if err != nil {
+		fatal("检查数据库版本失败", "error", err)
+	}
+	if pending > 0 {
+		if !cfg.DBAutoMigrate {
+			fatal("数据库有迁移尚未执行，且 TODO_DB_AUTO_MIGRATE 已关闭，请先执行 migrate up", "pending", pending)
+		}
+		applied, err := migrations.Up(database)
+		if err != nil {
+			fatal("执行数据库迁移失败", "error", err)
+		}
+		for _, m := range applied {
+			slog.Info("已执行数据库迁移", "version", m.Version, "name", m.Name)
+		}
 	}
 
 	// 4. 初始化 Repository (数据访问层)
 	// 将数据库连接注入到 Repository 中。所有数据库操作都通过 todoRepo 进行。
 	todoRepo := repo.NewTodoRepository(database)
+	outboxRepo := repo.NewOutboxRepository(database)
+	tokenRepo := repo.NewTokenRepository(database)
+	webhookSourceRepo := repo.NewWebhookSourceRepository(database)
+
+	// Webhook 重放缓存：默认保存在内存中，配置为 db 时保存在数据库中，重启后仍然有效。
+	var replayCache replay.Cache = replay.NewMemoryCache(cfg.WebhookReplayWindow)
+	if cfg.WebhookReplayStore == config.ReplayStoreDB {
+		replayCache = replay.NewDBCache(repo.NewNonceRepository(database), cfg.WebhookReplayWindow)
+	}
+
+	// 5. 初始化通知发送者
+	// 配置了 Apprise 时，通知先与待办事项一起写入数据库发件箱，再由后台 Worker 发送并在失败时重试；
+	// 否则 outbox 为 nil，Webhook 只更新待办事项。
+	var outbox *notify.Outbox
+	var apprise *notify.AppriseNotifier
+	if cfg.NotificationEnabled() {
+		apprise = notify.NewAppriseNotifier(cfg.AppriseURL, cfg.NotificationURLs)
+		outbox = notify.NewOutbox(outboxRepo, apprise, notify.OutboxOptions{
+			MaxAttempts: cfg.NotifyMaxAttempts,
+		})
+	}
 
-	// 5. 初始化 Router (路由层)
-	// 将配置和 Repository 注入到 Router 中。
+	// 配置重新加载：配置文件变化、收到 SIGHUP 或调用 POST /admin/reload 时重新读取全部配置，
+	// 日志级别与推送目标在这里替换，CORS 来源与 Webhook Secret 由 Router 替换。
+	reloader := reload.NewManager(cfg, func() (config.Config, error) {
+		src, _, err := config.ParseArgs(os.Args[1:])
+		if err != nil {
+			return config.Config{}, err
+		}
+		return config.Load(src)
+	})
+	reloader.OnReload(func(next config.Config) {
+		logging.SetLevel(next.LogLevel)
+		if apprise != nil {
+			apprise.SetTargets(next.AppriseURL, next.NotificationURLs)
+		}
+	})
+
+	// 6. 初始化 Router (路由层)
+	// 将配置、Repository、发件箱和事件广播器注入到 Router 中。
 	// Router 负责设置 HTTP 路由规则,并将请求分发给对应的 Handler。
-	engine := router.NewRouter(cfg, todoRepo)
+	broadcaster := events.NewBroadcaster(events.DefaultHistorySize)
+	engine := router.NewRouter(cfg, router.Deps{
+		TodoRepo:          todoRepo,
+		OutboxRepo:        outboxRepo,
+		TokenRepo:         tokenRepo,
+		WebhookSourceRepo: webhookSourceRepo,
+		Outbox:            outbox,
+		Broadcaster:       broadcaster,
+		ReplayCache:       replayCache,
+		Reloader:          reloader,
+	})
+
+	// 7. 启动后台 Worker
+	// workerCtx 在关闭服务时取消，workers 用于等待它们退出。
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	if outbox != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			outbox.Run(workerCtx)
+		}()
+	}
+	// 收到 SIGHUP 时重新加载配置；使用配置文件时还会定期检查文件是否变化
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reloader.WatchSignal(workerCtx, hangup)
+	}()
+	if src.File != "" && cfg.ConfigWatchInterval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			reloader.WatchFile(workerCtx, src.File, cfg.ConfigWatchInterval)
+		}()
+	}
+
+	// 8. 启动 Web 服务
+	// 使用显式的 http.Server 而不是 engine.Run()，以便设置超时并在收到退出信号时优雅关闭。
+	// SSE 与 WebSocket 是长连接，它们在建立连接后会自行解除读写超时。
+	server := &http.Server{
+		Addr:              cfg.BindAddr,
+		Handler:           engine,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTPReadTimeout,
+		WriteTimeout:      cfg.HTTPWriteTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+	// Shutdown 只等待普通请求完成。开始关闭时先断开 SSE 与 WebSocket 的事件订阅，
+	// 让这些长连接主动结束（客户端会重连到新的实例），否则 Shutdown 会一直等到排空期限。
+	server.RegisterOnShutdown(broadcaster.Close)
 
-	// 6. 启动 Web 服务
-	// Run() 方法会监听指定的端口(例如 :8080)并开始处理请求。
-	// 这不仅会阻塞当前 goroutine,还会监听中断信号以优雅关闭(虽然 Gin 默认 Run 实现比较简单,生产环境可能需要更复杂的优雅关闭逻辑)。
-	if err := engine.Run(cfg.BindAddr); err != nil {
-		log.Fatal(err)
+	// 配置了证书（或开发环境的自签名证书）时直接提供 HTTPS。
+	// 证书文件由 Reloader 定期检查，续期后无需重启即可生效。
+	if cfg.TLSEnabled() {
+		tlsConfig, certReloader, err := certs.ServerConfig(cfg.TLSOptions())
+		if err != nil {
+			fatal("加载 TLS 证书失败", "error", err)
+		}
+		server.TLSConfig = tlsConfig
+		if certReloader != nil {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				certReloader.Run(workerCtx)
+			}()
+		}
 	}
+
+	// 容器停止时会发送 SIGTERM，开发时按 Ctrl+C 发送 SIGINT
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			slog.Info("HTTPS 服务已启动", "bind_addr", cfg.BindAddr, "client_cert_required", cfg.ClientCertRequired())
+			// 证书由 TLSConfig 提供，这里不需要再传入文件路径
+			serveErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		slog.Info("HTTP 服务已启动", "bind_addr", cfg.BindAddr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		// 还没收到退出信号就返回，说明监听失败（例如端口被占用）
+		fatal("HTTP 服务异常退出", "error", err)
+	case <-ctx.Done():
+	}
+	// 恢复默认的信号处理：关闭过程中再次按 Ctrl+C 会立即退出
+	stop()
+
+	// 9. 按顺序关闭
+	// 先停止接收新请求并等待进行中的请求（例如正在写库的 Webhook）完成，
+	// 再停止后台 Worker，然后导出剩余的链路追踪数据，最后关闭数据库。
+	slog.Info("收到退出信号，开始关闭服务", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// 超过排空期限仍未完成的请求会被强制断开，Infisical 会按自己的策略重试
+		slog.Warn("等待进行中的请求超时，强制关闭剩余连接", "error", err)
+		_ = server.Close()
+	}
+
+	// 发件箱 Worker 会中断正在进行的发送，被中断的消息保持待发送状态，下次启动后继续发送
+	stopWorkers()
+	workers.Wait()
+
+	// 排空期限可能已经用完，导出链路追踪数据单独给一个较短的期限
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Warn("导出剩余的链路追踪数据失败", "error", err)
+	}
+
+	if sqlDB, err := database.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Warn("关闭数据库连接失败", "error", err)
+		}
+	}
+	slog.Info("服务已关闭")
+}
+
+// fatal 记录错误日志并以非零状态码退出程序，取代 log.Fatal，保证退出前的日志同样是结构化的。
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
@@ -8,6 +8,7 @@ import { Task } from './types';
 import { todoService } from './services/todoService';
 import { ApiException } from './services/api';
 import { usePolling } from './hooks/usePolling';
+import { useTodoStream } from './hooks/useTodoStream';
 import { useToast } from './hooks/useToast';
 import {
   Box,
@@ -62,6 +63,9 @@ function App() {
     }
   );
 
+  // 订阅后端实时推送，Webhook 创建或重置待办时立即刷新；轮询作为兜底
+  useTodoStream(silentLoadTasks, { enabled: !loading && !error });
+
   useEffect(() => {
     loadTasks();
   }, []);
@@ -100,8 +104,8 @@ function App() {
     ));
 
     try {
-      // 直接调用后端切换完成状态
-      const updated = await todoService.toggleComplete(id);
+      // 调用后端设置目标状态
+      const updated = await todoService.setComplete(id, nextStatus === 'done');
       setTasks(prev => prev.map(t => t.id === id ? updated : t));
     } catch (err) {
       // 回滚
@@ -3,6 +3,7 @@
 interface ImportMetaEnv {
   readonly VITE_API_BASE_URL: string;
   readonly VITE_POLL_INTERVAL_SECONDS?: string;
+  readonly VITE_AUTH_LOGIN_URL?: string;
 }
 
 interface ImportMeta {
@@ -0,0 +1,57 @@
+import { useEffect, useRef } from 'react';
+import { API_BASE_URL } from '../services/api';
+
+// 后端通过 SSE 推送的事件名称
+const TODO_EVENTS = [
+  'todo.created',
+  'todo.reset',
+  'todo.completed',
+  'todo.reopened',
+  'todo.deleted',
+  // 服务端无法补发错过的事件时发送，需要重新拉取列表
+  'resync',
+];
+
+interface UseTodoStreamOptions {
+  /** 是否启用订阅 */
+  enabled?: boolean;
+}
+
+/**
+ * 订阅后端的待办事项变更事件 (Server-Sent Events)
+ * 收到任意变更时调用 onChange，断线后由浏览器自动重连并携带 Last-Event-ID 补发
+ * @param onChange 收到变更时执行的回调函数
+ * @param options 订阅配置选项
+ */
+export function useTodoStream(
+  onChange: () => Promise<void>,
+  options: UseTodoStreamOptions = {}
+) {
+  const { enabled = true } = options;
+  const onChangeRef = useRef(onChange);
+
+  // 保持 callback 引用最新
+  useEffect(() => {
+    onChangeRef.current = onChange;
+  }, [onChange]);
+
+  useEffect(() => {
+    if (!enabled || typeof EventSource === 'undefined') {
+      return;
+    }
+
+    const source = new EventSource(`${API_BASE_URL}/stream`, { withCredentials: true });
+    const handleEvent = () => {
+      onChangeRef.current().catch((error) => {
+        console.warn('[Stream] 刷新任务列表失败:', error);
+      });
+    };
+    TODO_EVENTS.forEach((name) => source.addEventListener(name, handleEvent));
+
+    // 清理函数：组件卸载时关闭连接
+    return () => {
+      TODO_EVENTS.forEach((name) => source.removeEventListener(name, handleEvent));
+      source.close();
+    };
+  }, [enabled]);
+}
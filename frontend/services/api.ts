@@ -1,7 +1,9 @@
 import { z } from 'zod';
 import { ApiErrorSchema, createApiResponseSchema } from './types';
 
-const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api/todos';
+export const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080/api/todos';
+// 启用 OIDC 登录时配置，未登录 (401) 会跳转到该地址
+const AUTH_LOGIN_URL = import.meta.env.VITE_AUTH_LOGIN_URL;
 
 export class ApiException extends Error {
   constructor(
@@ -38,6 +40,8 @@ async function request<T>(
   try {
     const response = await fetch(url, {
       ...options,
+      // 携带会话 Cookie（开发环境前后端不同源时也需要）
+      credentials: 'include',
       headers: {
         'Content-Type': 'application/json',
         ...options?.headers,
@@ -54,6 +58,11 @@ async function request<T>(
       );
     }
 
+    if (response.status === 401 && AUTH_LOGIN_URL) {
+      const redirect = encodeURIComponent(window.location.pathname + window.location.search);
+      window.location.assign(`${AUTH_LOGIN_URL}?redirect=${redirect}`);
+    }
+
     if (!response.ok) {
       const errorResult = ApiErrorSchema.safeParse(json);
       const errorMessage = errorResult.success
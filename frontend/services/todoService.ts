@@ -18,8 +18,10 @@ export const todoService = {
     return todoItemToTask(dto);
   },
 
-  async toggleComplete(id: string): Promise<Task> {
-    const dto = await api.patch(`/${id}`, TodoItemDTOSchema);
+  // 显式设置目标状态，重复请求不会把状态切回去
+  async setComplete(id: string, completed: boolean): Promise<Task> {
+    const action = completed ? 'complete' : 'reopen';
+    const dto = await api.post(`/${id}/${action}`, TodoItemDTOSchema);
     return todoItemToTask(dto);
   },
 